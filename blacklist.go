@@ -0,0 +1,116 @@
+package scam_backoffice_rules
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// blacklistPatternPrefix marks an entry of a blacklist as a regular expression,
+// it is matched against a normalized symbol: "re:^u\$?sd[tc]?\d+$".
+const blacklistPatternPrefix = "re:"
+
+// Blacklist contains symbols known to be used by scam jettons.
+// Literal entries go through NormalizeString, so "Tether USD" and "tetherusd" are the same entry.
+type Blacklist struct {
+	// mu protects literals and patterns
+	mu       sync.RWMutex
+	literals map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// BlacklistSource provides blacklist entries to JettonVerifier.
+type BlacklistSource interface {
+	Blacklist(ctx context.Context) ([]string, error)
+}
+
+// FileBlacklistSource reads blacklist entries from a text file, one entry per line.
+// Empty lines and lines starting with "#" are ignored.
+type FileBlacklistSource struct {
+	Path string
+}
+
+// StaticBlacklistSource is a fixed in-memory list of blacklist entries.
+type StaticBlacklistSource []string
+
+func NewBlacklist(entries []string) (*Blacklist, error) {
+	blacklist := &Blacklist{}
+	if err := blacklist.Set(entries); err != nil {
+		return nil, err
+	}
+	return blacklist, nil
+}
+
+func mustBlacklist(entries []string) *Blacklist {
+	blacklist, err := NewBlacklist(entries)
+	if err != nil {
+		panic(err)
+	}
+	return blacklist
+}
+
+// Set replaces all entries of the blacklist.
+// Nothing is changed if any of the patterns doesn't compile.
+func (b *Blacklist) Set(entries []string) error {
+	literals := make(map[string]struct{}, len(entries))
+	var patterns []*regexp.Regexp
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, blacklistPatternPrefix) {
+			pattern, err := regexp.Compile(entry[len(blacklistPatternPrefix):])
+			if err != nil {
+				return fmt.Errorf("invalid blacklist pattern %v: %w", entry, err)
+			}
+			patterns = append(patterns, pattern)
+			continue
+		}
+		if normalized := NormalizeString(entry); normalized != "" {
+			literals[normalized] = struct{}{}
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.literals = literals
+	b.patterns = patterns
+	return nil
+}
+
+// Match returns the entry matching the normalized symbol.
+func (b *Blacklist) Match(normalized string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if _, ok := b.literals[normalized]; ok {
+		return normalized, true
+	}
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(normalized) {
+			return blacklistPatternPrefix + pattern.String(), true
+		}
+	}
+	return "", false
+}
+
+func (s *FileBlacklistSource) Blacklist(ctx context.Context) ([]string, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+func (s StaticBlacklistSource) Blacklist(ctx context.Context) ([]string, error) {
+	return append([]string(nil), s...), nil
+}
@@ -0,0 +1,91 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestBlacklist_Match(t *testing.T) {
+	blacklist, err := NewBlacklist([]string{"$USDT", "Tether USD", `re:^usd[tc]\d+$`})
+	require.Nil(t, err)
+
+	for _, symbol := range []string{"$usdt", "$ USDT", "Tether  USD", "TETHERUSD", "USDT2", "usdc7"} {
+		_, ok := blacklist.Match(NormalizeString(symbol))
+		require.True(t, ok, symbol)
+	}
+	for _, symbol := range []string{"usd", "usdt", "tether"} {
+		_, ok := blacklist.Match(NormalizeString(symbol))
+		require.False(t, ok, symbol)
+	}
+	entry, ok := blacklist.Match(NormalizeString("USDC7"))
+	require.True(t, ok)
+	require.Equal(t, `re:^usd[tc]\d+$`, entry)
+
+	_, err = NewBlacklist([]string{"re:("})
+	require.NotNil(t, err)
+	require.Nil(t, blacklist.Set([]string{"scam"}))
+	require.NotNil(t, blacklist.Set([]string{"usdt", "re:("}))
+	_, ok = blacklist.Match("scam")
+	require.True(t, ok, "a failed Set must not change the blacklist")
+}
+
+func TestFileBlacklistSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	require.Nil(t, os.WriteFile(path, []byte("# scam symbols\nUSDT\n\n  re:^not\\d+$  \n"), 0o644))
+
+	entries, err := (&FileBlacklistSource{Path: path}).Blacklist(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"USDT", `re:^not\d+$`}, entries)
+}
+
+type mutableBlacklistSource struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (s *mutableBlacklistSource) Blacklist(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries, nil
+}
+
+func (s *mutableBlacklistSource) set(entries ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+func TestJettonVerifier_WithBlacklist(t *testing.T) {
+	usdt := Jetton{Symbol: "USDT", Address: tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")}
+	fake := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000001")
+	source := &mutableBlacklistSource{entries: []string{"usdt", "re:^scam"}}
+
+	verifier := NewJettonVerifier(
+		WithJettonSources(StaticJettonSource{usdt}),
+		WithBlacklist(&Blacklist{}, source),
+	)
+	defer verifier.Close()
+	require.Nil(t, verifier.Refresh(context.Background()))
+
+	require.False(t, verifier.VerifySymbol(usdt.Address, "USDT").Blacklisted(), "the original jetton must not be blacklisted")
+	verdict := verifier.VerifySymbol(fake, "UsDT")
+	require.Equal(t, BlacklistedSymbol, verdict.Reason)
+	require.Equal(t, "usdt", verdict.Symbol)
+	require.Equal(t, BlacklistedSymbol, verifier.VerifySymbol(fake, "Scam Coin").Reason)
+
+	other := NewJettonVerifier(WithJettonSources(StaticJettonSource{usdt}))
+	defer other.Close()
+	require.Nil(t, other.Refresh(context.Background()))
+	require.False(t, other.VerifySymbol(fake, "Scam Coin").Blacklisted(), "blacklists of verifiers must be independent")
+
+	source.set("notcoin")
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.False(t, verifier.VerifySymbol(fake, "Scam Coin").Blacklisted())
+	require.True(t, verifier.VerifySymbol(fake, "NotCoin").Blacklisted())
+}
@@ -0,0 +1,246 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TypeOfScriptPolicy tells how characters of a unicode script are treated in jetton symbols.
+type TypeOfScriptPolicy string
+
+const (
+	// AllowScript allows characters of the script anywhere in a symbol.
+	AllowScript TypeOfScriptPolicy = "allow"
+	// AllowWholeSymbol allows characters of the script only if all letters of a symbol belong to it,
+	// so "ДОГС" is fine while "DОGS" with a cyrillic "О" is not.
+	AllowWholeSymbol TypeOfScriptPolicy = "whole_symbol"
+	// RejectScript disallows characters of the script.
+	RejectScript TypeOfScriptPolicy = "reject"
+)
+
+// ScriptPolicy configures a unicode script, category or property by its name,
+// like "Latin", "Cyrillic", "Lu" or "White_Space".
+type ScriptPolicy struct {
+	Name   string             `yaml:"name" json:"name"`
+	Policy TypeOfScriptPolicy `yaml:"policy" json:"policy"`
+}
+
+// CharsetConfig specifies what unicode characters are safe to be used in jetton symbols.
+type CharsetConfig struct {
+	// Scripts are checked in order, the first one containing a character decides its policy.
+	Scripts []ScriptPolicy `yaml:"scripts" json:"scripts"`
+	// Ranges are always allowed code point ranges like "U+2018-U+2019" or single code points like "U+20AE".
+	Ranges []string `yaml:"ranges" json:"ranges"`
+	// Chars are always allowed characters.
+	Chars string `yaml:"chars" json:"chars"`
+}
+
+type scriptTable struct {
+	name   string
+	table  *unicode.RangeTable
+	policy TypeOfScriptPolicy
+}
+
+type codePointRange struct {
+	lo, hi rune
+}
+
+// Charset is a compiled CharsetConfig.
+type Charset struct {
+	scripts []scriptTable
+	ranges  []codePointRange
+	chars   map[rune]struct{}
+}
+
+// DefaultCharsetConfig returns the configuration used by JettonVerifier without WithCharset.
+//
+// Unicode is so powerful that it is easy to trick a human into thinking that a scam jetton is a well-known one.
+// So blacklisting is kind of challenging.
+//
+// An ideal jetton should probably have a plain-English symbol, like "jUSDT".
+func DefaultCharsetConfig() CharsetConfig {
+	return CharsetConfig{
+		Scripts: []ScriptPolicy{ //ordered by popularity
+			{Name: "Latin", Policy: AllowScript},
+			{Name: "ASCII_Hex_Digit", Policy: AllowScript},
+			{Name: "White_Space", Policy: AllowScript},
+			{Name: "M", Policy: AllowScript},
+			{Name: "Dash", Policy: AllowScript},
+			{Name: "Cyrillic", Policy: AllowScript},
+			{Name: "Hyphen", Policy: AllowScript},
+			{Name: "Telugu", Policy: AllowScript},
+			{Name: "Devanagari", Policy: AllowScript},
+			{Name: "Katakana", Policy: AllowScript},
+		},
+		Ranges: []string{
+			"U+0021-U+0023", // ! " #
+			"U+0025-U+002A", // % & ' ( ) *
+			"U+002C-U+002F", // , - . /
+			"U+003A-U+003B", // : ;
+			"U+003F-U+0040", // ? @
+			"U+005B-U+005D", // [ \ ]
+			"U+2018-U+2019", // ‘ ’
+			"U+201C-U+201D", // “ ”
+		},
+		Chars: "_{}¡$+=`~₮❤人国币龱💎",
+	}
+}
+
+var defaultCharset = mustCharset(DefaultCharsetConfig())
+
+func NewCharset(config CharsetConfig) (*Charset, error) {
+	charset := &Charset{chars: map[rune]struct{}{}}
+	for _, script := range config.Scripts {
+		table := lookupRangeTable(script.Name)
+		if table == nil {
+			return nil, fmt.Errorf("unknown unicode script %v", script.Name)
+		}
+		switch script.Policy {
+		case AllowScript, AllowWholeSymbol, RejectScript:
+		default:
+			return nil, fmt.Errorf("unknown policy %v of script %v", script.Policy, script.Name)
+		}
+		charset.scripts = append(charset.scripts, scriptTable{name: script.Name, table: table, policy: script.Policy})
+	}
+	for _, value := range config.Ranges {
+		r, err := parseCodePointRange(value)
+		if err != nil {
+			return nil, err
+		}
+		charset.ranges = append(charset.ranges, r)
+	}
+	for _, c := range config.Chars {
+		charset.chars[c] = struct{}{}
+	}
+	return charset, nil
+}
+
+func mustCharset(config CharsetConfig) *Charset {
+	charset, err := NewCharset(config)
+	if err != nil {
+		panic(err)
+	}
+	return charset
+}
+
+// LoadCharset reads a CharsetConfig under the "charset" key.
+func LoadCharset(bytesOfCharset []byte, yamlConverted bool) (*Charset, error) {
+	var converted struct {
+		Charset CharsetConfig `yaml:"charset" json:"charset"`
+	}
+	var err error
+	if yamlConverted {
+		err = yaml.Unmarshal(bytesOfCharset, &converted)
+	} else {
+		err = json.Unmarshal(bytesOfCharset, &converted)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewCharset(converted.Charset)
+}
+
+// WithCharset replaces the default set of characters allowed in jetton symbols.
+func WithCharset(charset *Charset) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.charset = charset
+	}
+}
+
+func lookupRangeTable(name string) *unicode.RangeTable {
+	if table, ok := unicode.Scripts[name]; ok {
+		return table
+	}
+	if table, ok := unicode.Properties[name]; ok {
+		return table
+	}
+	return unicode.Categories[name]
+}
+
+func parseCodePointRange(value string) (codePointRange, error) {
+	lo, hi, isRange := strings.Cut(value, "-")
+	first, err := parseCodePoint(lo)
+	if err != nil {
+		return codePointRange{}, fmt.Errorf("invalid range %v: %w", value, err)
+	}
+	last := first
+	if isRange {
+		if last, err = parseCodePoint(hi); err != nil {
+			return codePointRange{}, fmt.Errorf("invalid range %v: %w", value, err)
+		}
+	}
+	if last < first {
+		return codePointRange{}, fmt.Errorf("invalid range %v: end is before start", value)
+	}
+	return codePointRange{lo: first, hi: last}, nil
+}
+
+func parseCodePoint(value string) (rune, error) {
+	value = strings.TrimSpace(value)
+	for _, prefix := range []string{"U+", "u+", "0x", "0X"} {
+		value = strings.TrimPrefix(value, prefix)
+	}
+	code, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	if code > unicode.MaxRune {
+		return 0, fmt.Errorf("code point %x is out of unicode", code)
+	}
+	return rune(code), nil
+}
+
+// explicit returns true if the character is allowed by Ranges or Chars.
+func (c *Charset) explicit(r rune) bool {
+	if _, ok := c.chars[r]; ok {
+		return true
+	}
+	for _, cr := range c.ranges {
+		if cr.lo <= r && r <= cr.hi {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Charset) script(r rune) (scriptTable, bool) {
+	for _, script := range c.scripts {
+		if unicode.Is(script.table, r) {
+			return script, true
+		}
+	}
+	return scriptTable{}, false
+}
+
+// Check returns the first character of the value which is not allowed.
+// The reason is DisallowedCharacter or MixedScripts for scripts configured with AllowWholeSymbol.
+func (c *Charset) Check(value string) (rune, TypeOfJettonReason, bool) {
+	for _, r := range value {
+		if c.explicit(r) {
+			continue
+		}
+		script, ok := c.script(r)
+		if !ok || script.policy == RejectScript {
+			return r, DisallowedCharacter, false
+		}
+		if script.policy == AllowWholeSymbol && !c.wholeSymbol(value, script.table) {
+			return r, MixedScripts, false
+		}
+	}
+	return 0, "", true
+}
+
+// wholeSymbol returns true if all letters of the value belong to the table.
+func (c *Charset) wholeSymbol(value string, table *unicode.RangeTable) bool {
+	for _, r := range value {
+		if unicode.IsLetter(r) && !unicode.Is(table, r) && !c.explicit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestCharset_Check(t *testing.T) {
+	charset, err := NewCharset(CharsetConfig{
+		Scripts: []ScriptPolicy{
+			{Name: "Latin", Policy: AllowScript},
+			{Name: "Nd", Policy: AllowScript},
+			{Name: "Greek", Policy: RejectScript},
+			{Name: "Cyrillic", Policy: AllowWholeSymbol},
+			{Name: "Arabic", Policy: AllowWholeSymbol},
+		},
+		Ranges: []string{"U+0020", "0x2D-0x2E"},
+		Chars:  "$₮",
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		value      string
+		wantRune   rune
+		wantReason TypeOfJettonReason
+	}{
+		{value: "USDT"},
+		{value: "$USD₮ 2.0"},
+		{value: "ДОГС"},
+		{value: "ДОГС-2"},
+		{value: "دولار"},
+		{value: "DОGS", wantRune: 'О', wantReason: MixedScripts},
+		{value: "USDب", wantRune: 'ب', wantReason: MixedScripts},
+		{value: "ΑΒΓ", wantRune: 'Α', wantReason: DisallowedCharacter},
+		{value: "USDT!", wantRune: '!', wantReason: DisallowedCharacter},
+		{value: "币", wantRune: '币', wantReason: DisallowedCharacter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			r, reason, ok := charset.Check(tt.value)
+			require.Equal(t, tt.wantReason == "", ok)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantRune, r)
+		})
+	}
+}
+
+func TestNewCharset_Errors(t *testing.T) {
+	for _, config := range []CharsetConfig{
+		{Scripts: []ScriptPolicy{{Name: "Klingon", Policy: AllowScript}}},
+		{Scripts: []ScriptPolicy{{Name: "Latin", Policy: "maybe"}}},
+		{Ranges: []string{"U+0030-U+0020"}},
+		{Ranges: []string{"U+ZZ"}},
+		{Ranges: []string{"U+110000"}},
+	} {
+		_, err := NewCharset(config)
+		require.NotNil(t, err, config)
+	}
+}
+
+func TestLoadCharset(t *testing.T) {
+	charset, err := LoadCharset([]byte(`
+charset:
+  scripts:
+    - name: Latin
+      policy: allow
+    - name: Han
+      policy: whole_symbol
+  ranges:
+    - U+0030-U+0039
+  chars: " $"
+`), true)
+	require.Nil(t, err)
+	_, _, ok := charset.Check("$USDT 100")
+	require.True(t, ok)
+	_, _, ok = charset.Check("人民币")
+	require.True(t, ok)
+	_, reason, _ := charset.Check("USD币")
+	require.Equal(t, MixedScripts, reason)
+
+	_, err = LoadCharset([]byte(`{"charset": {"scripts": [{"name": "Arabic", "policy": "allow"}]}}`), false)
+	require.Nil(t, err)
+}
+
+func TestJettonVerifier_WithCharset(t *testing.T) {
+	charset, err := NewCharset(CharsetConfig{Scripts: []ScriptPolicy{
+		{Name: "Latin", Policy: AllowScript},
+		{Name: "Arabic", Policy: AllowWholeSymbol},
+	}})
+	require.Nil(t, err)
+	address := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000001")
+
+	verifier := NewJettonVerifier(WithJettonSources(StaticJettonSource{}), WithCharset(charset))
+	defer verifier.Close()
+	require.False(t, verifier.VerifySymbol(address, "دولار").Blacklisted())
+	verdict := verifier.VerifySymbol(address, "USDب")
+	require.Equal(t, MixedScripts, verdict.Reason)
+	require.Equal(t, SymbolField, verdict.Field)
+
+	defaultVerifier := NewJettonVerifier(WithJettonSources(StaticJettonSource{}))
+	defer defaultVerifier.Close()
+	require.Equal(t, DisallowedCharacter, defaultVerifier.VerifySymbol(address, "دولار").Reason)
+}
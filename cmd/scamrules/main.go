@@ -0,0 +1,83 @@
+// Command scamrules exposes debugging helpers of the scam_backoffice_rules package.
+//
+// Usage:
+//
+//	scamrules trace [-comment] <text>
+//	scamrules imagehash <file>
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	rules "github.com/tonkeeper/scam_backoffice_rules"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "trace":
+		trace(os.Args[2:])
+	case "imagehash":
+		imageHash(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: scamrules trace [-comment] <text>")
+	fmt.Fprintln(os.Stderr, "       scamrules imagehash <file>")
+	os.Exit(2)
+}
+
+func trace(args []string) {
+	flags := flag.NewFlagSet("trace", flag.ExitOnError)
+	comment := flags.Bool("comment", false, "trace NormalizeComment instead of NormalizeString")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		usage()
+	}
+	text := strings.Join(flags.Args(), " ")
+
+	var result rules.NormalizationTrace
+	if *comment {
+		result = rules.TraceNormalizeComment(text)
+	} else {
+		result = rules.TraceNormalizeString(text)
+	}
+	printJSON(result)
+}
+
+// imageHash prints perceptual hashes of a local image to build an index of protected logos.
+func imageHash(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	hashes, err := rules.ComputeImageHashes(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	printJSON(hashes)
+}
+
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,176 @@
+package scam_backoffice_rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+// Operation codes of message bodies carrying comments.
+const (
+	textCommentOpCode            uint32 = 0x00000000
+	encryptedCommentOpCode       uint32 = 0x2167da4b
+	jettonTransferOpCode         uint32 = 0x0f8a7ea5
+	jettonNotifyOpCode           uint32 = 0x7362d09c
+	jettonInternalTransferOpCode uint32 = 0x178d4519
+	nftTransferOpCode            uint32 = 0x5fcc3d14
+	nftOwnershipAssignedOpCode   uint32 = 0x05138d91
+)
+
+// TypeOfCommentSource is a message carrying a comment.
+type TypeOfCommentSource string
+
+const (
+	// TextMessage is a plain text comment with op 0.
+	TextMessage TypeOfCommentSource = "text"
+	// JettonTransferMessage is a forward_payload of a jetton transfer, internal_transfer or transfer_notification.
+	JettonTransferMessage TypeOfCommentSource = "jetton_transfer"
+	// NftTransferMessage is a forward_payload of an NFT transfer or ownership_assigned.
+	NftTransferMessage TypeOfCommentSource = "nft_transfer"
+)
+
+// ErrNoComment is returned when a message body doesn't carry a text comment.
+var ErrNoComment = errors.New("message has no comment")
+
+// MessageComment is a comment extracted from a message body.
+type MessageComment struct {
+	Text   string              `json:"text"`
+	Source TypeOfCommentSource `json:"source"`
+	// Encrypted comments (op 0x2167da4b) can't be read, Text is empty for them.
+	Encrypted bool `json:"encrypted"`
+}
+
+type jettonTransferBody struct {
+	QueryId             uint64
+	Amount              tlb.VarUInteger16
+	Destination         tlb.MsgAddress
+	ResponseDestination tlb.MsgAddress
+	CustomPayload       tlb.Maybe[tlb.Ref[tlb.Any]]
+	ForwardTonAmount    tlb.VarUInteger16
+	ForwardPayload      tlb.EitherRef[tlb.Any]
+}
+
+type jettonNotifyBody struct {
+	QueryId        uint64
+	Amount         tlb.VarUInteger16
+	Sender         tlb.MsgAddress
+	ForwardPayload tlb.EitherRef[tlb.Any]
+}
+
+type jettonInternalTransferBody struct {
+	QueryId          uint64
+	Amount           tlb.VarUInteger16
+	From             tlb.MsgAddress
+	ResponseAddress  tlb.MsgAddress
+	ForwardTonAmount tlb.VarUInteger16
+	ForwardPayload   tlb.EitherRef[tlb.Any]
+}
+
+type nftTransferBody struct {
+	QueryId             uint64
+	NewOwner            tlb.MsgAddress
+	ResponseDestination tlb.MsgAddress
+	CustomPayload       tlb.Maybe[tlb.Ref[tlb.Any]]
+	ForwardAmount       tlb.VarUInteger16
+	ForwardPayload      tlb.EitherRef[tlb.Any]
+}
+
+type nftOwnershipAssignedBody struct {
+	QueryId        uint64
+	PrevOwner      tlb.MsgAddress
+	ForwardPayload tlb.EitherRef[tlb.Any]
+}
+
+// ExtractMessageComment returns a comment of the message body.
+func ExtractMessageComment(msg tlb.Message) (MessageComment, error) {
+	body := boc.Cell(msg.Body.Value)
+	return ExtractComment(&body)
+}
+
+// ExtractComment returns a text comment of a message body cell.
+// Comments in forward payloads of jetton and NFT transfers are extracted too.
+// ErrNoComment is returned for bodies without comments.
+// The body is read from the beginning through a shallow copy, so the read position of the root cell is kept,
+// but cells referenced by the body, like a forward payload in a ref, are shared and read in place.
+func ExtractComment(body *boc.Cell) (MessageComment, error) {
+	if body == nil {
+		return MessageComment{}, ErrNoComment
+	}
+	copied := *body
+	body = &copied
+	body.ResetCounters()
+	if body.BitsAvailableForRead() < 32 {
+		return MessageComment{}, ErrNoComment
+	}
+	op, err := body.ReadUint(32)
+	if err != nil {
+		return MessageComment{}, err
+	}
+	var payload tlb.EitherRef[tlb.Any]
+	var source TypeOfCommentSource
+	switch uint32(op) {
+	case textCommentOpCode, encryptedCommentOpCode:
+		return decodeComment(uint32(op), body, TextMessage)
+	case jettonTransferOpCode:
+		var msg jettonTransferBody
+		err = tlb.Unmarshal(body, &msg)
+		payload, source = msg.ForwardPayload, JettonTransferMessage
+	case jettonNotifyOpCode:
+		var msg jettonNotifyBody
+		err = tlb.Unmarshal(body, &msg)
+		payload, source = msg.ForwardPayload, JettonTransferMessage
+	case jettonInternalTransferOpCode:
+		var msg jettonInternalTransferBody
+		err = tlb.Unmarshal(body, &msg)
+		payload, source = msg.ForwardPayload, JettonTransferMessage
+	case nftTransferOpCode:
+		var msg nftTransferBody
+		err = tlb.Unmarshal(body, &msg)
+		payload, source = msg.ForwardPayload, NftTransferMessage
+	case nftOwnershipAssignedOpCode:
+		var msg nftOwnershipAssignedBody
+		err = tlb.Unmarshal(body, &msg)
+		payload, source = msg.ForwardPayload, NftTransferMessage
+	default:
+		return MessageComment{}, ErrNoComment
+	}
+	if err != nil {
+		return MessageComment{}, fmt.Errorf("failed to decode %v message: %w", source, err)
+	}
+	forward := boc.Cell(payload.Value)
+	if forward.BitsAvailableForRead() < 32 {
+		return MessageComment{}, ErrNoComment
+	}
+	op, err = forward.ReadUint(32)
+	if err != nil {
+		return MessageComment{}, err
+	}
+	return decodeComment(uint32(op), &forward, source)
+}
+
+// decodeComment reads a text or an encrypted comment after its op code.
+func decodeComment(op uint32, cell *boc.Cell, source TypeOfCommentSource) (MessageComment, error) {
+	switch op {
+	case textCommentOpCode:
+		var text tlb.Text
+		if err := tlb.Unmarshal(cell, &text); err != nil {
+			return MessageComment{}, fmt.Errorf("failed to decode comment: %w", err)
+		}
+		return MessageComment{Text: string(text), Source: source}, nil
+	case encryptedCommentOpCode:
+		return MessageComment{Source: source, Encrypted: true}, nil
+	}
+	return MessageComment{}, ErrNoComment
+}
+
+// CheckMessageBody extracts a comment of the message body and checks it with rules.
+// UnKnown is returned for bodies without readable comments.
+func CheckMessageBody(rules Rules, body *boc.Cell) TypeOfAction {
+	comment, err := ExtractComment(body)
+	if err != nil || comment.Encrypted {
+		return UnKnown
+	}
+	return CheckActionOfType(rules, comment.Text, Comment)
+}
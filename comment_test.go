@@ -0,0 +1,147 @@
+package scam_backoffice_rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+func commentCell(t *testing.T, op uint32, text string) *boc.Cell {
+	cell := boc.NewCell()
+	require.Nil(t, cell.WriteUint(uint64(op), 32))
+	require.Nil(t, tlb.Marshal(cell, tlb.Text(text)))
+	return cell
+}
+
+func transferCell(t *testing.T, op uint32, body any) *boc.Cell {
+	cell := boc.NewCell()
+	require.Nil(t, cell.WriteUint(uint64(op), 32))
+	require.Nil(t, tlb.Marshal(cell, body))
+	return cell
+}
+
+func TestExtractComment(t *testing.T) {
+	account := tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	address := account.ToMsgAddress()
+	long := strings.Repeat("claim your reward at scam.com ", 20)
+	payload := func(cell *boc.Cell, isRight bool) tlb.EitherRef[tlb.Any] {
+		return tlb.EitherRef[tlb.Any]{IsRight: isRight, Value: tlb.Any(*cell)}
+	}
+
+	tests := []struct {
+		name       string
+		body       *boc.Cell
+		want       MessageComment
+		wantErr    error
+		wantAnyErr bool
+	}{
+		{name: "text", body: commentCell(t, textCommentOpCode, "hello"), want: MessageComment{Text: "hello", Source: TextMessage}},
+		{name: "snake", body: commentCell(t, textCommentOpCode, long), want: MessageComment{Text: long, Source: TextMessage}},
+		{name: "encrypted", body: commentCell(t, encryptedCommentOpCode, "ciphertext"), want: MessageComment{Source: TextMessage, Encrypted: true}},
+		{
+			name: "jetton transfer",
+			body: transferCell(t, jettonTransferOpCode, jettonTransferBody{
+				Destination: address, ResponseDestination: address,
+				ForwardPayload: payload(commentCell(t, textCommentOpCode, long), true),
+			}),
+			want: MessageComment{Text: long, Source: JettonTransferMessage},
+		},
+		{
+			name: "jetton notify inline",
+			body: transferCell(t, jettonNotifyOpCode, jettonNotifyBody{
+				Sender:         address,
+				ForwardPayload: payload(commentCell(t, textCommentOpCode, "gift"), false),
+			}),
+			want: MessageComment{Text: "gift", Source: JettonTransferMessage},
+		},
+		{
+			name: "nft ownership assigned encrypted",
+			body: transferCell(t, nftOwnershipAssignedOpCode, nftOwnershipAssignedBody{
+				PrevOwner:      address,
+				ForwardPayload: payload(commentCell(t, encryptedCommentOpCode, "secret"), true),
+			}),
+			want: MessageComment{Source: NftTransferMessage, Encrypted: true},
+		},
+		{
+			name: "nft transfer without payload",
+			body: transferCell(t, nftTransferOpCode, nftTransferBody{
+				NewOwner: address, ResponseDestination: address,
+				ForwardPayload: payload(boc.NewCell(), false),
+			}),
+			wantErr: ErrNoComment,
+		},
+		{name: "empty", body: boc.NewCell(), wantErr: ErrNoComment},
+		{name: "unknown op", body: commentCell(t, 0xdeadbeef, "hello"), wantErr: ErrNoComment},
+		{name: "truncated transfer", body: commentCell(t, jettonTransferOpCode, "x"), wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment, err := ExtractComment(tt.body)
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.wantAnyErr:
+				require.NotNil(t, err)
+			default:
+				require.Nil(t, err)
+				require.Equal(t, tt.want, comment)
+			}
+		})
+	}
+}
+
+func TestExtractComment_Boc(t *testing.T) {
+	cells, err := boc.DeserializeBocHex("b5ee9c7201010101000e00001800000000776f6f3071387762")
+	require.Nil(t, err)
+	comment, err := ExtractComment(cells[0])
+	require.Nil(t, err)
+	require.Equal(t, "woo0q8wb", comment.Text)
+
+	comment, err = ExtractMessageComment(tlb.Message{Body: tlb.EitherRef[tlb.Any]{Value: tlb.Any(*cells[0])}})
+	require.Nil(t, err)
+	require.Equal(t, "woo0q8wb", comment.Text)
+}
+
+func TestCheckMessageBody(t *testing.T) {
+	rules := LoadRules([]byte(`
+rules:
+  - pattern: "airdrop"
+    action: mark_scam
+    type: comment
+`), true)
+	require.Equal(t, MarkScam, CheckMessageBody(rules, commentCell(t, textCommentOpCode, "Free AIRDROP")))
+	require.Equal(t, UnKnown, CheckMessageBody(rules, commentCell(t, encryptedCommentOpCode, "airdrop")))
+	require.Equal(t, UnKnown, CheckMessageBody(rules, boc.NewCell()))
+}
+
+func TestExtractComment_KeepsPosition(t *testing.T) {
+	body := commentCell(t, textCommentOpCode, "hello")
+	_, err := body.ReadUint(8)
+	require.Nil(t, err)
+	available := body.BitsAvailableForRead()
+
+	comment, err := ExtractComment(body)
+	require.Nil(t, err)
+	require.Equal(t, "hello", comment.Text)
+	require.Equal(t, available, body.BitsAvailableForRead())
+
+	// a forward payload in a ref is read in place, but the root position is kept
+	account := tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	body = transferCell(t, jettonNotifyOpCode, jettonNotifyBody{
+		Sender:         account.ToMsgAddress(),
+		ForwardPayload: tlb.EitherRef[tlb.Any]{IsRight: true, Value: tlb.Any(*commentCell(t, textCommentOpCode, "gift"))},
+	})
+	_, err = body.ReadUint(32)
+	require.Nil(t, err)
+	available = body.BitsAvailableForRead()
+	for i := 0; i < 2; i++ {
+		comment, err = ExtractComment(body)
+		require.Nil(t, err)
+		require.Equal(t, MessageComment{Text: "gift", Source: JettonTransferMessage}, comment)
+		require.Equal(t, available, body.BitsAvailableForRead())
+	}
+}
@@ -0,0 +1,79 @@
+package scam_backoffice_rules
+
+// levenshtein returns the edit distance between two strings counted in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// damerauLevenshtein returns the optimal string alignment distance between two strings counted in runes:
+// like levenshtein, but a swap of two adjacent runes costs 1.
+// Only the last three rows of the matrix are kept.
+func damerauLevenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = minInt(cur[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(rb)]
+}
+
+// isTransposition returns true if b is a with two adjacent runes swapped.
+func isTransposition(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) != len(rb) {
+		return false
+	}
+	first := -1
+	for i := range ra {
+		if ra[i] == rb[i] {
+			continue
+		}
+		if first >= 0 {
+			return i == first+1 && ra[first] == rb[i] && ra[i] == rb[first] && string(ra[i+1:]) == string(rb[i+1:])
+		}
+		first = i
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TypeOfDNSZone is a zone of names resolved by TON DNS.
+type TypeOfDNSZone string
+
+const (
+	// TonZone contains .ton domains, they are NFT items of the TON DNS collection.
+	TonZone TypeOfDNSZone = "ton"
+	// TelegramZone contains telegram usernames as "username.t.me", they are NFT items sold on Fragment.
+	TelegramZone TypeOfDNSZone = "t.me"
+)
+
+// dnsZones are ordered so that a longer zone is tried first.
+var dnsZones = []TypeOfDNSZone{TelegramZone, TonZone}
+
+// ProtectedDNSName is a .ton domain or a .t.me username of a well-known brand.
+type ProtectedDNSName struct {
+	Brand string `yaml:"brand" json:"brand"`
+	Name  string `yaml:"name" json:"name"`
+}
+
+// DefaultProtectedDNSNames is used when no other list is configured.
+var DefaultProtectedDNSNames = []ProtectedDNSName{
+	{Brand: "Tonkeeper", Name: "tonkeeper.ton"},
+	{Brand: "Tonkeeper", Name: "tonkeeper.t.me"},
+	{Brand: "TON", Name: "foundation.ton"},
+	{Brand: "TON", Name: "toncoin.t.me"},
+	{Brand: "Getgems", Name: "getgems.ton"},
+	{Brand: "Getgems", Name: "getgems.t.me"},
+	{Brand: "Fragment", Name: "fragment.t.me"},
+	{Brand: "Wallet", Name: "wallet.t.me"},
+}
+
+// DNSName is a name in one of the TON DNS zones.
+type DNSName struct {
+	// Name is the whole name in lower case with decoded punycode, lookalike characters are kept.
+	Name string        `json:"name"`
+	Zone TypeOfDNSZone `json:"zone"`
+	// Label is the label right before the zone, "tonkeeper" for "wallet.tonkeeper.ton".
+	Label string `json:"label"`
+}
+
+// ParseDNSName splits a .ton domain or a .t.me username into a label and a zone.
+// An NFT name of a telegram username like "@tonkeeper" is parsed as "tonkeeper.t.me".
+func ParseDNSName(name string) (DNSName, bool) {
+	name = exactDomain(name)
+	if strings.HasPrefix(name, "@") {
+		name = name[1:] + "." + string(TelegramZone)
+	}
+	for _, zone := range dnsZones {
+		suffix := "." + string(zone)
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		labels := strings.Split(strings.TrimSuffix(name, suffix), ".")
+		label := labels[len(labels)-1]
+		if label == "" {
+			return DNSName{}, false
+		}
+		return DNSName{Name: name, Zone: zone, Label: label}, true
+	}
+	return DNSName{}, false
+}
+
+// ExtractDNSNames returns .ton domains and .t.me usernames mentioned in a text.
+// Links like "t.me/username" are telegram handles, see ExtractTelegramHandles.
+func ExtractDNSNames(text string) []DNSName {
+	var names []DNSName
+	for _, url := range ExtractURLs(text) {
+		if name, ok := ParseDNSName(url.Host); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DNSMatch describes a DNS name which imitates a protected one.
+type DNSMatch struct {
+	Name      DNSName          `json:"name"`
+	Protected ProtectedDNSName `json:"protected"`
+	// Distance is the edit distance between label skeletons, 0 means a pure homograph.
+	Distance int `json:"distance"`
+}
+
+func (m DNSMatch) String() string {
+	return fmt.Sprintf("%v impersonates %v (distance %v)", m.Name.Name, m.Protected.Name, m.Distance)
+}
+
+// DNSNameVerifier finds .ton domains and .t.me usernames imitating protected names
+// with lookalike characters or small typos, like "tonkeeрer.ton" (cyrillic "р") imitating "tonkeeper.ton".
+// Labels are compared as skeletons like in HomographDetector.
+// A name in another zone is reported too, so every zone owned by a brand should be protected.
+type DNSNameVerifier struct {
+	// mu protects protected
+	mu        sync.RWMutex
+	protected []protectedDNSName
+	// maxDistance limits the tolerated edit distance, a negative value means it depends on the label length.
+	maxDistance int
+}
+
+type protectedDNSName struct {
+	ProtectedDNSName
+	name  DNSName
+	label string
+}
+
+type DNSNameOption func(v *DNSNameVerifier)
+
+// WithDNSMaxDistance sets the same edit distance tolerance for all protected names.
+func WithDNSMaxDistance(distance int) DNSNameOption {
+	return func(v *DNSNameVerifier) {
+		v.maxDistance = distance
+	}
+}
+
+func NewDNSNameVerifier(names []ProtectedDNSName, opts ...DNSNameOption) *DNSNameVerifier {
+	verifier := &DNSNameVerifier{maxDistance: -1}
+	for _, o := range opts {
+		o(verifier)
+	}
+	verifier.SetProtectedNames(names)
+	return verifier
+}
+
+// SetProtectedNames replaces the list of protected names, names outside of TON DNS zones are skipped.
+func (v *DNSNameVerifier) SetProtectedNames(names []ProtectedDNSName) {
+	protected := make([]protectedDNSName, 0, len(names))
+	for _, name := range names {
+		parsed, ok := ParseDNSName(name.Name)
+		if !ok {
+			continue
+		}
+		protected = append(protected, protectedDNSName{
+			ProtectedDNSName: name,
+			name:             parsed,
+			label:            labelSkeleton(parsed.Label),
+		})
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.protected = protected
+}
+
+// LoadProtectedNames replaces the list of protected names with the given yaml or json document:
+//
+//	protected_dns_names:
+//	  - brand: Tonkeeper
+//	    name: tonkeeper.ton
+func (v *DNSNameVerifier) LoadProtectedNames(bytesOfNames []byte, yamlConverted bool) error {
+	var converted struct {
+		ProtectedDNSNames []ProtectedDNSName `yaml:"protected_dns_names" json:"protected_dns_names"`
+	}
+	var err error
+	if yamlConverted {
+		err = yaml.Unmarshal(bytesOfNames, &converted)
+	} else {
+		err = json.Unmarshal(bytesOfNames, &converted)
+	}
+	if err != nil {
+		return err
+	}
+	v.SetProtectedNames(converted.ProtectedDNSNames)
+	return nil
+}
+
+// Check returns a protected name imitated by the given one.
+// The protected names themselves and their subdomains are never reported.
+func (v *DNSNameVerifier) Check(name DNSName) (DNSMatch, bool) {
+	skeleton := labelSkeleton(name.Label)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, protected := range v.protected {
+		if name.Name == protected.name.Name || strings.HasSuffix(name.Name, "."+protected.name.Name) {
+			return DNSMatch{}, false
+		}
+	}
+	best, found := DNSMatch{}, false
+	for _, protected := range v.protected {
+		distance := levenshtein(skeleton, protected.label)
+		if distance > labelTolerance(v.maxDistance, protected.label) || (found && distance >= best.Distance) {
+			continue
+		}
+		best, found = DNSMatch{Name: name, Protected: protected.ProtectedDNSName, Distance: distance}, true
+	}
+	return best, found
+}
+
+// CheckName parses a DNS name, for example a name of an NFT item, and checks it.
+func (v *DNSNameVerifier) CheckName(name string) (DNSMatch, bool) {
+	parsed, ok := ParseDNSName(name)
+	if !ok {
+		return DNSMatch{}, false
+	}
+	return v.Check(parsed)
+}
+
+// CheckText returns the first DNS name mentioned in a text which imitates a protected one.
+// The text must not be normalized, otherwise lookalike characters are already replaced.
+func (v *DNSNameVerifier) CheckText(text string) (DNSMatch, bool) {
+	for _, name := range ExtractDNSNames(text) {
+		if match, ok := v.Check(name); ok {
+			return match, true
+		}
+	}
+	return DNSMatch{}, false
+}
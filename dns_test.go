@@ -0,0 +1,93 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNSName(t *testing.T) {
+	tests := []struct {
+		name      string
+		want      DNSName
+		wantFound bool
+	}{
+		{name: "Tonkeeper.ton", want: DNSName{Name: "tonkeeper.ton", Zone: TonZone, Label: "tonkeeper"}, wantFound: true},
+		{name: "wallet.tonkeeper.ton.", want: DNSName{Name: "wallet.tonkeeper.ton", Zone: TonZone, Label: "tonkeeper"}, wantFound: true},
+		{name: "durov.t.me", want: DNSName{Name: "durov.t.me", Zone: TelegramZone, Label: "durov"}, wantFound: true},
+		{name: "@durov", want: DNSName{Name: "durov.t.me", Zone: TelegramZone, Label: "durov"}, wantFound: true},
+		{name: "xn--tonkeeer-bch.ton", want: DNSName{Name: "tonkeeрer.ton", Zone: TonZone, Label: "tonkeeрer"}, wantFound: true},
+		{name: "t.me", wantFound: false},
+		{name: "tonkeeper.com", wantFound: false},
+		{name: "Tonkeeper", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, found := ParseDNSName(tt.name)
+			require.Equal(t, tt.wantFound, found)
+			require.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestDNSNameVerifier_CheckName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantName  string
+		wantFound bool
+	}{
+		{name: "tonkeeper.ton", wantFound: false},
+		{name: "wallet.tonkeeper.ton", wantFound: false},
+		{name: "@tonkeeper", wantFound: false},
+		{name: "tonkeeрer.ton", wantName: "tonkeeper.ton", wantFound: true},
+		{name: "xn--tonkeeer-bch.ton", wantName: "tonkeeper.ton", wantFound: true},
+		{name: "t0nkeeper.ton", wantName: "tonkeeper.ton", wantFound: true},
+		{name: "tonkeepr.ton", wantName: "tonkeeper.ton", wantFound: true},
+		{name: "tonkeeper.fake.ton", wantFound: false},
+		{name: "getgerns.t.me", wantName: "getgems.ton", wantFound: true},
+		{name: "@wa11et", wantName: "wallet.t.me", wantFound: true},
+		{name: "foundation.t.me", wantName: "foundation.ton", wantFound: true},
+		{name: "subbotin.ton", wantFound: false},
+		{name: "tonkeeper.com", wantFound: false},
+	}
+	verifier := NewDNSNameVerifier(DefaultProtectedDNSNames)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, found := verifier.CheckName(tt.name)
+			require.Equal(t, tt.wantFound, found)
+			if found {
+				require.Equal(t, tt.wantName, match.Protected.Name)
+			}
+		})
+	}
+}
+
+func TestDNSNameVerifier_CheckText(t *testing.T) {
+	verifier := NewDNSNameVerifier(nil, WithDNSMaxDistance(0))
+	require.Nil(t, verifier.LoadProtectedNames([]byte(`{"protected_dns_names": [{"brand": "Tonkeeper", "name": "tonkeeper.ton"}]}`), false))
+
+	match, found := verifier.CheckText("Send TON to tonkeeрer . ton to get a gift")
+	require.True(t, found)
+	require.Equal(t, "tonkeeper.ton", match.Protected.Name)
+	require.Equal(t, 0, match.Distance)
+
+	_, found = verifier.CheckText("Send TON to tonkeepr.ton to get a gift")
+	require.False(t, found)
+	_, found = verifier.CheckText("Thanks for using tonkeeper.ton")
+	require.False(t, found)
+}
+
+func TestImpersonatesDNSRule(t *testing.T) {
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: impersonates_dns
+    action: mark_scam
+    type: all
+`), true)
+
+	require.Equal(t, MarkScam, CheckActionOfType(rules, "Claim your airdrop at tonkeeрer.ton", Comment))
+	require.Equal(t, UnKnown, CheckActionOfType(rules, "Claim your airdrop at tonkeeper.ton", Comment))
+	require.Equal(t, MarkScam, CheckNft(rules, NftMetadata{Name: "@t0nkeeper"}).Action)
+	require.Equal(t, UnKnown, CheckNft(rules, NftMetadata{Name: "@durov"}).Action)
+	require.Equal(t, UnKnown, CheckNft(rules, NftMetadata{Description: "@t0nkeeper"}).Action)
+}
@@ -0,0 +1,119 @@
+package scam_backoffice_rules
+
+import (
+	"math/big"
+
+	"github.com/tonkeeper/tongo"
+)
+
+const tonDecimals = 9
+
+// TransferVerdict is the result of DustClassifier.
+type TransferVerdict struct {
+	// Action is UnKnown unless the transfer is dust from an unknown sender.
+	Action TypeOfAction `json:"action"`
+	Dust   bool         `json:"dust"`
+	// KnownSender means the sender is one of the counterparties of the transfer.
+	KnownSender bool `json:"known_sender"`
+	// Threshold is the dust threshold of the asset in its units, like "1/100" for 0.01 TON.
+	Threshold string `json:"threshold,omitempty"`
+}
+
+// Combine merges the verdict with an action of rules for the same transfer.
+// Rules accepting or marking a transfer as scam win, so payment ids are accepted even with tiny amounts,
+// otherwise dust from unknown senders gets the classifier action.
+func (v TransferVerdict) Combine(ruleAction TypeOfAction) TypeOfAction {
+	switch {
+	case ruleAction == Accept || ruleAction == MarkScam:
+		return ruleAction
+	case v.Action != UnKnown && v.Action != "":
+		return v.Action
+	default:
+		return ruleAction
+	}
+}
+
+// DustClassifier drops near-zero-value transfers usually used to deliver comment spam.
+type DustClassifier struct {
+	ton           *big.Rat
+	jettons       map[tongo.AccountID]*big.Rat
+	defaultJetton *big.Rat
+	action        TypeOfAction
+}
+
+type DustOption func(c *DustClassifier)
+
+// WithDustThreshold configures the threshold of an asset in its units, nil jetton means TON.
+// Transfers below the threshold are dust, by default it is 0.01 TON and jettons are not checked.
+func WithDustThreshold(jetton *tongo.AccountID, threshold *big.Rat) DustOption {
+	return func(c *DustClassifier) {
+		if jetton == nil {
+			c.ton = threshold
+			return
+		}
+		c.jettons[*jetton] = threshold
+	}
+}
+
+// WithDefaultJettonDustThreshold configures the threshold of jettons without their own one.
+func WithDefaultJettonDustThreshold(threshold *big.Rat) DustOption {
+	return func(c *DustClassifier) {
+		c.defaultJetton = threshold
+	}
+}
+
+// WithDustAction configures the action for dust transfers, Drop by default.
+func WithDustAction(action TypeOfAction) DustOption {
+	return func(c *DustClassifier) {
+		c.action = action
+	}
+}
+
+func NewDustClassifier(opts ...DustOption) *DustClassifier {
+	c := &DustClassifier{
+		ton:     big.NewRat(1, 100),
+		jettons: map[tongo.AccountID]*big.Rat{},
+		action:  Drop,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Classify checks the amount of the transfer against the threshold of its asset.
+// Transfers from the counterparties of the transfer are never dust.
+func (c *DustClassifier) Classify(transfer Transfer) TransferVerdict {
+	verdict := TransferVerdict{Action: UnKnown}
+	for _, counterparty := range transfer.Counterparties {
+		if counterparty == transfer.Sender {
+			verdict.KnownSender = true
+			return verdict
+		}
+	}
+	threshold, decimals := c.ton, tonDecimals
+	if transfer.Jetton != nil {
+		threshold, decimals = c.defaultJetton, transfer.Decimals
+		if t, ok := c.jettons[*transfer.Jetton]; ok {
+			threshold = t
+		}
+	}
+	if threshold == nil {
+		return verdict
+	}
+	verdict.Threshold = threshold.RatString()
+	amount := new(big.Rat)
+	if transfer.Amount != nil {
+		amount.SetFrac(transfer.Amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	}
+	if amount.Cmp(threshold) < 0 {
+		verdict.Dust = true
+		verdict.Action = c.action
+	}
+	return verdict
+}
+
+// ClassifyTransfer combines CheckTransfer with the dust classifier.
+func ClassifyTransfer(rules Rules, classifier *DustClassifier, transfer Transfer) TypeOfAction {
+	return classifier.Classify(transfer).Combine(CheckTransfer(rules, transfer))
+}
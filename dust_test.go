@@ -0,0 +1,67 @@
+package scam_backoffice_rules
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestDustClassifier_Classify(t *testing.T) {
+	usdt := tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	other := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000002")
+	sender := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000001")
+	classifier := NewDustClassifier(WithDustThreshold(&usdt, big.NewRat(1, 10)))
+
+	tests := []struct {
+		name       string
+		transfer   Transfer
+		wantDust   bool
+		wantAction TypeOfAction
+	}{
+		{name: "ton dust", transfer: Transfer{Sender: sender, Amount: big.NewInt(1_000_000)}, wantDust: true, wantAction: Drop},
+		{name: "no amount", transfer: Transfer{Sender: sender}, wantDust: true, wantAction: Drop},
+		{name: "ton", transfer: Transfer{Sender: sender, Amount: big.NewInt(10_000_000)}, wantAction: UnKnown},
+		{name: "known sender", transfer: Transfer{Sender: sender, Amount: big.NewInt(1), Counterparties: []tongo.AccountID{sender}}, wantAction: UnKnown},
+		{name: "jetton dust", transfer: Transfer{Sender: sender, Jetton: &usdt, Decimals: 6, Amount: big.NewInt(99_999)}, wantDust: true, wantAction: Drop},
+		{name: "jetton", transfer: Transfer{Sender: sender, Jetton: &usdt, Decimals: 6, Amount: big.NewInt(100_000)}, wantAction: UnKnown},
+		{name: "unconfigured jetton", transfer: Transfer{Sender: sender, Jetton: &other, Decimals: 9, Amount: big.NewInt(1)}, wantAction: UnKnown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := classifier.Classify(tt.transfer)
+			require.Equal(t, tt.wantDust, verdict.Dust)
+			require.Equal(t, tt.wantAction, verdict.Action)
+		})
+	}
+
+	classifier = NewDustClassifier(WithDefaultJettonDustThreshold(big.NewRat(1, 1)), WithDustAction(MarkScam))
+	verdict := classifier.Classify(Transfer{Sender: sender, Jetton: &other, Decimals: 9, Amount: big.NewInt(1)})
+	require.True(t, verdict.Dust)
+	require.Equal(t, MarkScam, verdict.Action)
+	require.Equal(t, "1", verdict.Threshold)
+}
+
+func TestClassifyTransfer(t *testing.T) {
+	sender := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000001")
+	rules := LoadRules([]byte(`
+rules:
+  - pattern: "^order-\\d+$"
+    action: accept
+    type: comment
+  - pattern: "airdrop"
+    action: mark_scam
+    type: comment
+`), true)
+	classifier := NewDustClassifier()
+	dust := big.NewInt(1000)
+
+	require.Equal(t, Drop, ClassifyTransfer(rules, classifier, Transfer{Sender: sender, Amount: dust, Comment: "hello"}))
+	require.Equal(t, Accept, ClassifyTransfer(rules, classifier, Transfer{Sender: sender, Amount: dust, Comment: "order-42"}))
+	require.Equal(t, MarkScam, ClassifyTransfer(rules, classifier, Transfer{Sender: sender, Amount: dust, Comment: "free airdrop"}))
+	require.Equal(t, UnKnown, ClassifyTransfer(rules, classifier, Transfer{Sender: sender, Amount: big.NewInt(1_000_000_000), Comment: "hello"}))
+	require.Equal(t, UnKnown, ClassifyTransfer(rules, classifier, Transfer{
+		Sender: sender, Amount: dust, Comment: "hello", Counterparties: []tongo.AccountID{sender},
+	}))
+}
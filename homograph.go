@@ -0,0 +1,220 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProtectedDomain is a domain of a well-known brand which scammers like to imitate.
+type ProtectedDomain struct {
+	Brand  string `yaml:"brand" json:"brand"`
+	Domain string `yaml:"domain" json:"domain"`
+}
+
+// DefaultProtectedDomains is used when no other list is configured.
+var DefaultProtectedDomains = []ProtectedDomain{
+	{Brand: "Tonkeeper", Domain: "tonkeeper.com"},
+	{Brand: "TON", Domain: "ton.org"},
+	{Brand: "Getgems", Domain: "getgems.io"},
+	{Brand: "Tonviewer", Domain: "tonviewer.com"},
+	{Brand: "Fragment", Domain: "fragment.com"},
+}
+
+// skeletonConfusables are characters which look alike in a domain but survive NormalizeString.
+var skeletonConfusables = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+	"1", "l",
+	"i", "l",
+	"3", "e",
+	"5", "s",
+	"4", "a",
+	"7", "t",
+	"8", "b",
+)
+
+// DomainSkeleton returns a confusable skeleton of a domain.
+// Punycode is decoded and every label goes through NormalizeString,
+// so "tonkeeрer.com" (cyrillic "р"), "xn--tonkeeer-bch.com", "ton-keeper.com" and "t0nkeeper.com"
+// share the skeleton of "tonkeeper.com".
+func DomainSkeleton(domain string) string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(domain), "."), ".")
+	for i, label := range labels {
+		labels[i] = labelSkeleton(decodeDomain(label))
+	}
+	return strings.Join(labels, ".")
+}
+
+// exactDomain is a domain with decoded punycode but without any rune mapping,
+// so a lookalike of a protected domain is not equal to it.
+func exactDomain(domain string) string {
+	domain = decodeDomain(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), "."))
+	return strings.TrimPrefix(domain, "www.")
+}
+
+func labelSkeleton(label string) string {
+	return skeletonConfusables.Replace(NormalizeString(label))
+}
+
+// HomographMatch describes a domain which imitates a protected one.
+type HomographMatch struct {
+	Domain    string          `json:"domain"`
+	Protected ProtectedDomain `json:"protected"`
+	// Distance is the edit distance between skeletons, 0 means a pure homograph.
+	Distance int `json:"distance"`
+}
+
+// HomographDetector finds domains imitating protected brands with lookalike characters or small typos.
+type HomographDetector struct {
+	// mu protects protected
+	mu        sync.RWMutex
+	protected []protectedSkeleton
+	// maxDistance limits the tolerated edit distance, a negative value means it depends on the label length.
+	maxDistance int
+}
+
+type protectedSkeleton struct {
+	ProtectedDomain
+	exact string
+	// exactLabel is the label right before the TLD ("tonkeeper" for "tonkeeper.com").
+	exactLabel string
+	// label is the skeleton of exactLabel.
+	label string
+}
+
+// minDistinctiveLabelLength is the minimum length of a protected label which is a brand on its own,
+// so its exact copy in another registrable domain ("tonkeeper.app") is reported,
+// while short words like "ton" are used by many legitimate domains ("ton.app", "ton.vote").
+const minDistinctiveLabelLength = 5
+
+type HomographOption func(d *HomographDetector)
+
+// WithMaxDistance sets the same edit distance tolerance for all protected domains.
+func WithMaxDistance(distance int) HomographOption {
+	return func(d *HomographDetector) {
+		d.maxDistance = distance
+	}
+}
+
+func NewHomographDetector(domains []ProtectedDomain, opts ...HomographOption) *HomographDetector {
+	detector := &HomographDetector{maxDistance: -1}
+	for _, o := range opts {
+		o(detector)
+	}
+	detector.SetProtectedDomains(domains)
+	return detector
+}
+
+// SetProtectedDomains replaces the list of protected domains.
+func (d *HomographDetector) SetProtectedDomains(domains []ProtectedDomain) {
+	protected := make([]protectedSkeleton, 0, len(domains))
+	for _, domain := range domains {
+		exact := exactDomain(domain.Domain)
+		labels := strings.Split(exact, ".")
+		if len(labels) < 2 {
+			continue
+		}
+		label := labels[len(labels)-2]
+		protected = append(protected, protectedSkeleton{
+			ProtectedDomain: domain,
+			exact:           exact,
+			exactLabel:      label,
+			label:           labelSkeleton(label),
+		})
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.protected = protected
+}
+
+// LoadProtectedDomains replaces the list of protected domains with the given yaml or json document:
+//
+//	protected_domains:
+//	  - brand: Tonkeeper
+//	    domain: tonkeeper.com
+func (d *HomographDetector) LoadProtectedDomains(bytesOfDomains []byte, yamlConverted bool) error {
+	var converted struct {
+		ProtectedDomains []ProtectedDomain `yaml:"protected_domains" json:"protected_domains"`
+	}
+	var err error
+	if yamlConverted {
+		err = yaml.Unmarshal(bytesOfDomains, &converted)
+	} else {
+		err = json.Unmarshal(bytesOfDomains, &converted)
+	}
+	if err != nil {
+		return err
+	}
+	d.SetProtectedDomains(converted.ProtectedDomains)
+	return nil
+}
+
+// Check returns a protected domain imitated by the given domain.
+// The protected domains themselves and their subdomains are never reported.
+// A label is reported only if it is a lookalike of a protected label,
+// an exact copy is reported only as the registrable label of a distinctive brand like "tonkeeper.app".
+func (d *HomographDetector) Check(domain string) (HomographMatch, bool) {
+	exact := exactDomain(domain)
+	labels := strings.Split(exact, ".")
+	labels = labels[:len(labels)-1]
+	skeletons := make([]string, 0, len(labels))
+	for _, label := range labels {
+		skeletons = append(skeletons, labelSkeleton(label))
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, protected := range d.protected {
+		if exact == protected.exact || strings.HasSuffix(exact, "."+protected.exact) {
+			return HomographMatch{}, false
+		}
+	}
+	best, found := HomographMatch{}, false
+	for _, protected := range d.protected {
+		tolerance := labelTolerance(d.maxDistance, protected.label)
+		for i, skeleton := range skeletons {
+			if labels[i] == protected.exactLabel {
+				registrable := i == len(labels)-1
+				if !registrable || utf8.RuneCountInString(protected.exactLabel) < minDistinctiveLabelLength {
+					continue
+				}
+			}
+			distance := levenshtein(skeleton, protected.label)
+			if distance > tolerance || (found && distance >= best.Distance) {
+				continue
+			}
+			best, found = HomographMatch{Domain: exact, Protected: protected.ProtectedDomain, Distance: distance}, true
+		}
+	}
+	return best, found
+}
+
+// CheckText returns the first domain mentioned in a text which imitates a protected domain.
+// The text must not be normalized, otherwise lookalike characters are already replaced.
+func (d *HomographDetector) CheckText(text string) (HomographMatch, bool) {
+	for _, url := range ExtractURLs(text) {
+		if match, ok := d.Check(url.Host); ok {
+			return match, true
+		}
+	}
+	return HomographMatch{}, false
+}
+
+// labelTolerance returns maxDistance or, if it is negative, an edit distance tolerated for a label of this length.
+func labelTolerance(maxDistance int, label string) int {
+	if maxDistance >= 0 {
+		return maxDistance
+	}
+	switch length := utf8.RuneCountInString(label); {
+	case length < 5:
+		return 0
+	case length < 9:
+		return 1
+	default:
+		return 2
+	}
+}
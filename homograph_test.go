@@ -0,0 +1,62 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHomographDetector_Check(t *testing.T) {
+	tests := []struct {
+		domain    string
+		wantBrand string
+		wantFound bool
+	}{
+		{domain: "tonkeeper.com", wantFound: false},
+		{domain: "app.tonkeeper.com", wantFound: false},
+		{domain: "tonkeeрer.com", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "xn--tonkeeer-bch.com", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "t0nkeeper.com", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "t0nkeeper.app", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "ton-keeper.io", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "tonkeepr.com", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "tonkeeper.app", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "tonkeepeer.claim-gift.xyz", wantBrand: "Tonkeeper", wantFound: true},
+		{domain: "tonkeeper.claim-gift.xyz", wantFound: false},
+		{domain: "getgerns.io", wantBrand: "Getgems", wantFound: true},
+		{domain: "t0n.org", wantBrand: "TON", wantFound: true},
+		{domain: "tan.org", wantFound: false},
+		{domain: "ton.app", wantFound: false},
+		{domain: "ton.vote", wantFound: false},
+		{domain: "docs.ton.app", wantFound: false},
+		{domain: "wallet.ton.app", wantFound: false},
+		{domain: "tоn.app", wantBrand: "TON", wantFound: true},
+		{domain: "google.com", wantFound: false},
+	}
+	detector := NewHomographDetector(DefaultProtectedDomains)
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			match, found := detector.Check(tt.domain)
+			require.Equal(t, tt.wantFound, found)
+			require.Equal(t, tt.wantBrand, match.Protected.Brand)
+		})
+	}
+}
+
+func TestHomographRule(t *testing.T) {
+	detector := NewHomographDetector(nil)
+	require.Nil(t, detector.LoadProtectedDomains([]byte(`
+protected_domains:
+  - brand: Tonkeeper
+    domain: tonkeeper.com
+`), true))
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: homograph
+    action: mark_scam
+`), true, WithHomographDetector(detector))
+
+	require.Equal(t, MarkScam, CheckAction(rules, "Your wallet is locked, restore it at tonkeeрer.com"))
+	require.Equal(t, UnKnown, CheckAction(rules, "Download the wallet at tonkeeper.com"))
+	require.Equal(t, UnKnown, CheckAction(rules, "ton.org"))
+}
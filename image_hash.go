@@ -0,0 +1,446 @@
+package scam_backoffice_rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/gommon/log"
+	"github.com/tonkeeper/tongo"
+	"gopkg.in/yaml.v3"
+)
+
+// TypeOfImageHash is an algorithm of a perceptual hash.
+type TypeOfImageHash string
+
+const (
+	// AverageHash compares pixels of an 8x8 thumbnail with their mean, it survives recoloring and scaling.
+	AverageHash TypeOfImageHash = "ahash"
+	// DifferenceHash compares neighbouring pixels of a 9x8 thumbnail, it survives changes of brightness.
+	DifferenceHash TypeOfImageHash = "dhash"
+	// PerceptualHash compares low frequencies of a DCT of a 32x32 thumbnail with their median,
+	// it survives small crops and compression.
+	PerceptualHash TypeOfImageHash = "phash"
+)
+
+// imageHashes are all supported algorithms in the order they are computed.
+var imageHashes = []TypeOfImageHash{AverageHash, DifferenceHash, PerceptualHash}
+
+// defaultHammingThresholds are the maximum numbers of differing bits of similar images.
+var defaultHammingThresholds = map[TypeOfImageHash]int{
+	AverageHash:    4,
+	DifferenceHash: 8,
+	PerceptualHash: 10,
+}
+
+// ErrUnknownImageHash is returned for an unsupported hash algorithm.
+var ErrUnknownImageHash = errors.New("unknown image hash")
+
+// ErrImageTooLarge is returned for images with more pixels than allowed, they are not decoded.
+var ErrImageTooLarge = errors.New("image is too large")
+
+// defaultMaxImagePixels is the maximum number of pixels of a decoded image, a 4096x4096 logo is far beyond it.
+// A small PNG can declare huge dimensions, so the limit is checked before decoding.
+const defaultMaxImagePixels = 4096 * 4096
+
+// thumbnailSamples is the number of samples per side of a thumbnail pixel.
+const thumbnailSamples = 4
+
+// ImageHash is a 64-bit perceptual hash of an image.
+// Its text form is "<kind>:<16 hex digits>", like "phash:c3e1f0f8d0e0c0c0".
+type ImageHash struct {
+	Kind TypeOfImageHash
+	Hash uint64
+}
+
+// ParseImageHash parses the text form of a hash.
+func ParseImageHash(s string) (ImageHash, error) {
+	kind, hash, ok := strings.Cut(s, ":")
+	if !ok || !isImageHash(TypeOfImageHash(kind)) {
+		return ImageHash{}, fmt.Errorf("%w: %q", ErrUnknownImageHash, s)
+	}
+	value, err := strconv.ParseUint(hash, 16, 64)
+	if err != nil {
+		return ImageHash{}, fmt.Errorf("image hash %q: %w", s, err)
+	}
+	return ImageHash{Kind: TypeOfImageHash(kind), Hash: value}, nil
+}
+
+func isImageHash(kind TypeOfImageHash) bool {
+	_, ok := defaultHammingThresholds[kind]
+	return ok
+}
+
+func (h ImageHash) String() string {
+	return fmt.Sprintf("%v:%016x", h.Kind, h.Hash)
+}
+
+func (h ImageHash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *ImageHash) UnmarshalText(text []byte) error {
+	parsed, err := ParseImageHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Distance returns the Hamming distance between hashes of the same kind.
+func (h ImageHash) Distance(other ImageHash) (int, error) {
+	if h.Kind != other.Kind {
+		return 0, fmt.Errorf("can't compare %v with %v", h.Kind, other.Kind)
+	}
+	return bits.OnesCount64(h.Hash ^ other.Hash), nil
+}
+
+// ComputeImageHashes decodes a PNG, JPEG or GIF image and returns its hashes of all kinds.
+// Images larger than 4096x4096 pixels are rejected with ErrImageTooLarge.
+func ComputeImageHashes(data []byte) ([]ImageHash, error) {
+	return computeImageHashes(data, defaultMaxImagePixels)
+}
+
+func computeImageHashes(data []byte, maxPixels int) ([]ImageHash, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > int64(maxPixels) {
+		return nil, fmt.Errorf("%w: %vx%v", ErrImageTooLarge, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	hashes := make([]ImageHash, 0, len(imageHashes))
+	for _, kind := range imageHashes {
+		hash, err := HashImage(img, kind)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// HashImage returns a perceptual hash of a decoded image.
+func HashImage(img image.Image, kind TypeOfImageHash) (ImageHash, error) {
+	var hash uint64
+	switch kind {
+	case AverageHash:
+		pixels := grayThumbnail(img, 8, 8)
+		mean := 0.0
+		for _, p := range pixels {
+			mean += p
+		}
+		mean /= float64(len(pixels))
+		for i, p := range pixels {
+			if p > mean {
+				hash |= 1 << uint(i)
+			}
+		}
+	case DifferenceHash:
+		pixels := grayThumbnail(img, 9, 8)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				if pixels[y*9+x] > pixels[y*9+x+1] {
+					hash |= 1 << uint(y*8+x)
+				}
+			}
+		}
+	case PerceptualHash:
+		coefficients := lowFrequencies(dct2D(grayThumbnail(img, 32, 32), 32), 32, 8)
+		// the DC coefficient is the mean brightness, it is not a part of the median
+		sorted := append([]float64(nil), coefficients[1:]...)
+		sort.Float64s(sorted)
+		median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+		for i, c := range coefficients {
+			if c > median {
+				hash |= 1 << uint(i)
+			}
+		}
+	default:
+		return ImageHash{}, fmt.Errorf("%w: %v", ErrUnknownImageHash, kind)
+	}
+	return ImageHash{Kind: kind, Hash: hash}, nil
+}
+
+// grayThumbnail scales an image down to width x height averaging luminance of a few samples of covered pixels.
+// Transparent pixels are blended with white, logos are usually shown on a light background.
+func grayThumbnail(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]float64, width*height)
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return sums
+	}
+	// large images are sampled, small ones are read pixel by pixel
+	sw, sh := minInt(w, width*thumbnailSamples), minInt(h, height*thumbnailSamples)
+	for sy := 0; sy < sh; sy++ {
+		y := (2*sy + 1) * h / (2 * sh)
+		ty := sy * height / sh
+		for sx := 0; sx < sw; sx++ {
+			x := (2*sx + 1) * w / (2 * sw)
+			tx := sx * width / sw
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			white := float64(0xffff - a)
+			luminance := 0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)
+			sums[ty*width+tx] += luminance / 0xffff
+			counts[ty*width+tx]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= counts[i]
+		} else if i > 0 {
+			// an image smaller than the thumbnail, repeat the previous pixel
+			sums[i] = sums[i-1]
+		}
+	}
+	return sums
+}
+
+// dct2D returns a type-II discrete cosine transform of a size x size matrix.
+func dct2D(pixels []float64, size int) []float64 {
+	cosines := make([]float64, size*size)
+	for k := 0; k < size; k++ {
+		for n := 0; n < size; n++ {
+			cosines[k*size+n] = math.Cos(math.Pi / float64(size) * (float64(n) + 0.5) * float64(k))
+		}
+	}
+	rows := make([]float64, size*size)
+	for y := 0; y < size; y++ {
+		for k := 0; k < size; k++ {
+			sum := 0.0
+			for n := 0; n < size; n++ {
+				sum += pixels[y*size+n] * cosines[k*size+n]
+			}
+			rows[y*size+k] = sum
+		}
+	}
+	result := make([]float64, size*size)
+	for x := 0; x < size; x++ {
+		for k := 0; k < size; k++ {
+			sum := 0.0
+			for n := 0; n < size; n++ {
+				sum += rows[n*size+x] * cosines[k*size+n]
+			}
+			result[k*size+x] = sum
+		}
+	}
+	return result
+}
+
+// lowFrequencies returns the top-left corner x corner coefficients of a size x size matrix.
+func lowFrequencies(coefficients []float64, size, corner int) []float64 {
+	result := make([]float64, 0, corner*corner)
+	for y := 0; y < corner; y++ {
+		result = append(result, coefficients[y*size:y*size+corner]...)
+	}
+	return result
+}
+
+// TypeOfImageAsset is a kind of an asset with a protected logo.
+type TypeOfImageAsset string
+
+const (
+	JettonImage     TypeOfImageAsset = "jetton"
+	CollectionImage TypeOfImageAsset = "collection"
+)
+
+// ProtectedImage is a logo of a well-known jetton or collection.
+type ProtectedImage struct {
+	Asset   TypeOfImageAsset `yaml:"asset" json:"asset"`
+	Address tongo.AccountID  `yaml:"-" json:"address"`
+	Name    string           `yaml:"name" json:"name"`
+	Hashes  []ImageHash      `yaml:"hashes" json:"hashes"`
+}
+
+// ImageMatch describes an image similar to a protected logo.
+type ImageMatch struct {
+	Protected ProtectedImage  `json:"protected"`
+	Kind      TypeOfImageHash `json:"kind"`
+	// Distance is the Hamming distance between hashes of the Kind.
+	Distance int `json:"distance"`
+}
+
+func (m ImageMatch) String() string {
+	return fmt.Sprintf("image copies %v %v (%v) with %v distance %v", m.Protected.Asset, m.Protected.Name, m.Protected.Address.ToRaw(), m.Kind, m.Distance)
+}
+
+// ImageHashIndex keeps perceptual hashes of logos of well-known jettons and collections
+// and finds assets reusing them, even slightly cropped or recolored.
+// It works offline, hashes of protected logos are computed from local files or loaded from a document.
+type ImageHashIndex struct {
+	// mu protects images
+	mu     sync.RWMutex
+	images []ProtectedImage
+	// thresholds are the maximum Hamming distances of similar images by hash kind.
+	thresholds map[TypeOfImageHash]int
+	// maxPixels is the maximum number of pixels of images hashed by AddImage and CheckImage.
+	maxPixels int
+}
+
+type ImageHashIndexOption func(i *ImageHashIndex)
+
+// WithHammingThreshold configures the maximum Hamming distance of similar images for a hash kind.
+// A negative distance disables the kind. By default, it is 4 for ahash, 8 for dhash and 10 for phash.
+func WithHammingThreshold(kind TypeOfImageHash, distance int) ImageHashIndexOption {
+	return func(i *ImageHashIndex) {
+		i.thresholds[kind] = distance
+	}
+}
+
+// WithMaxImagePixels configures the maximum width*height of images hashed by AddImage and CheckImage,
+// larger images are rejected with ErrImageTooLarge before decoding. By default, it is 4096*4096.
+// Non-positive values are ignored.
+func WithMaxImagePixels(pixels int) ImageHashIndexOption {
+	return func(i *ImageHashIndex) {
+		if pixels <= 0 {
+			log.Warnf("ignoring non-positive image pixels limit %v, keeping %v", pixels, i.maxPixels)
+			return
+		}
+		i.maxPixels = pixels
+	}
+}
+
+func NewImageHashIndex(opts ...ImageHashIndexOption) *ImageHashIndex {
+	index := &ImageHashIndex{
+		thresholds: make(map[TypeOfImageHash]int, len(defaultHammingThresholds)),
+		maxPixels:  defaultMaxImagePixels,
+	}
+	for kind, distance := range defaultHammingThresholds {
+		index.thresholds[kind] = distance
+	}
+	for _, o := range opts {
+		o(index)
+	}
+	return index
+}
+
+// Add adds a protected logo with precomputed hashes.
+func (i *ImageHashIndex) Add(image ProtectedImage) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.images = append(i.images, image)
+}
+
+// AddImage computes hashes of a logo and adds it to the index.
+func (i *ImageHashIndex) AddImage(asset TypeOfImageAsset, address tongo.AccountID, name string, data []byte) error {
+	hashes, err := computeImageHashes(data, i.maxPixels)
+	if err != nil {
+		return err
+	}
+	i.Add(ProtectedImage{Asset: asset, Address: address, Name: name, Hashes: hashes})
+	return nil
+}
+
+// Images returns a copy of all protected logos, it can be saved and loaded later with Load.
+func (i *ImageHashIndex) Images() []ProtectedImage {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]ProtectedImage(nil), i.images...)
+}
+
+// Load replaces protected logos with the given json document:
+//
+//	{"images": [{"asset": "jetton", "name": "Tether USD", "address": "0:b113...", "hashes": ["phash:c3e1f0f8d0e0c0c0"]}]}
+//
+// The yaml form is accepted too when yamlConverted is set.
+func (i *ImageHashIndex) Load(bytesOfImages []byte, yamlConverted bool) error {
+	var converted struct {
+		Images []ProtectedImage `json:"images"`
+	}
+	var err error
+	if yamlConverted {
+		err = unmarshalProtectedImagesYAML(bytesOfImages, &converted.Images)
+	} else {
+		err = json.Unmarshal(bytesOfImages, &converted)
+	}
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.images = converted.Images
+	return nil
+}
+
+// unmarshalProtectedImagesYAML decodes addresses by hand, tongo.AccountID is not a yaml scalar.
+func unmarshalProtectedImagesYAML(data []byte, images *[]ProtectedImage) error {
+	var converted struct {
+		Images []struct {
+			ProtectedImage `yaml:",inline"`
+			Address        string `yaml:"address"`
+		} `yaml:"images"`
+	}
+	if err := yaml.Unmarshal(data, &converted); err != nil {
+		return err
+	}
+	result := make([]ProtectedImage, 0, len(converted.Images))
+	for _, item := range converted.Images {
+		address, err := tongo.ParseAccountID(item.Address)
+		if err != nil {
+			return fmt.Errorf("image %v: %w", item.Name, err)
+		}
+		item.ProtectedImage.Address = address
+		result = append(result, item.ProtectedImage)
+	}
+	*images = result
+	return nil
+}
+
+// Match returns the closest protected logo of another asset within the Hamming threshold of any hash kind.
+// Logos of the asset itself are never reported.
+func (i *ImageHashIndex) Match(address tongo.AccountID, hashes []ImageHash) (ImageMatch, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	best, found := ImageMatch{}, false
+	for _, protected := range i.images {
+		if protected.Address == address {
+			continue
+		}
+		for _, known := range protected.Hashes {
+			threshold, ok := i.thresholds[known.Kind]
+			if !ok || threshold < 0 {
+				continue
+			}
+			for _, hash := range hashes {
+				distance, err := hash.Distance(known)
+				if err != nil || distance > threshold {
+					continue
+				}
+				// distances of different kinds are compared relative to their thresholds
+				if found && float64(distance)/float64(threshold+1) >= float64(best.Distance)/float64(i.thresholds[best.Kind]+1) {
+					continue
+				}
+				best, found = ImageMatch{Protected: protected, Kind: known.Kind, Distance: distance}, true
+			}
+		}
+	}
+	return best, found
+}
+
+// CheckImage hashes an image of an asset and returns the protected logo it copies.
+func (i *ImageHashIndex) CheckImage(address tongo.AccountID, data []byte) (ImageMatch, bool, error) {
+	hashes, err := computeImageHashes(data, i.maxPixels)
+	if err != nil {
+		return ImageMatch{}, false, err
+	}
+	match, ok := i.Match(address, hashes)
+	return match, ok, nil
+}
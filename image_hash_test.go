@@ -0,0 +1,183 @@
+package scam_backoffice_rules
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+// testLogo draws a ring with a bar and a corner mark, like a coin logo, with the given colors.
+func testLogo(size int, foreground, background color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	center, outer, inner := float64(size)/2, float64(size)*0.45, float64(size)*0.3
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			distance := dx*dx + dy*dy
+			bar := x > size*2/5 && x < size*3/5 && y > size/4 && y < size*3/4
+			mark := x+y < size/3
+			if (distance < outer*outer && distance > inner*inner) || bar || mark {
+				img.Set(x, y, foreground)
+			} else {
+				img.Set(x, y, background)
+			}
+		}
+	}
+	return img
+}
+
+// testPattern draws diagonal stripes unrelated to testLogo.
+func testPattern(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if (x+y)/(size/6)%2 == 0 {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.Nil(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.Nil(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70}))
+	return buf.Bytes()
+}
+
+func TestImageHash_Text(t *testing.T) {
+	hash, err := ParseImageHash("phash:c3e1f0f8d0e0c0c0")
+	require.Nil(t, err)
+	require.Equal(t, ImageHash{Kind: PerceptualHash, Hash: 0xc3e1f0f8d0e0c0c0}, hash)
+	require.Equal(t, "phash:c3e1f0f8d0e0c0c0", hash.String())
+
+	_, err = ParseImageHash("md5:c3e1f0f8d0e0c0c0")
+	require.ErrorIs(t, err, ErrUnknownImageHash)
+	_, err = ParseImageHash("phash:xyz")
+	require.NotNil(t, err)
+
+	distance, err := hash.Distance(ImageHash{Kind: PerceptualHash, Hash: 0xc3e1f0f8d0e0c0c1})
+	require.Nil(t, err)
+	require.Equal(t, 1, distance)
+	_, err = hash.Distance(ImageHash{Kind: AverageHash})
+	require.NotNil(t, err)
+}
+
+func TestImageHashIndex_CheckImage(t *testing.T) {
+	usdt := tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe")
+	fake := tongo.MustParseAccountID("0:ca9006bd3fb03d355daeeff93b24be90afaa6e3ca0073ff5720f8a852c933278")
+	green, white := color.RGBA{R: 0x26, G: 0xa1, B: 0x7b, A: 0xff}, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	logo := testLogo(256, green, white)
+
+	index := NewImageHashIndex()
+	require.Nil(t, index.AddImage(JettonImage, usdt, "Tether USD", encodePNG(t, logo)))
+
+	tests := []struct {
+		name      string
+		address   tongo.AccountID
+		image     []byte
+		wantFound bool
+	}{
+		{name: "original", address: usdt, image: encodePNG(t, logo)},
+		{name: "same image", address: fake, image: encodePNG(t, logo), wantFound: true},
+		{name: "jpeg", address: fake, image: encodeJPEG(t, logo), wantFound: true},
+		{name: "scaled", address: fake, image: encodePNG(t, testLogo(100, green, white)), wantFound: true},
+		{name: "recolored", address: fake, image: encodePNG(t, testLogo(256, color.RGBA{R: 0x20, G: 0x60, B: 0xc0, A: 0xff}, white)), wantFound: true},
+		{name: "cropped", address: fake, image: encodePNG(t, logo.SubImage(image.Rect(6, 6, 250, 250))), wantFound: true},
+		{name: "unrelated", address: fake, image: encodePNG(t, testPattern(256))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, found, err := index.CheckImage(tt.address, tt.image)
+			require.Nil(t, err)
+			require.Equal(t, tt.wantFound, found, match.String())
+			if found {
+				require.Equal(t, usdt, match.Protected.Address)
+			}
+		})
+	}
+
+	_, _, err := index.CheckImage(fake, []byte("<svg></svg>"))
+	require.NotNil(t, err)
+}
+
+func TestImageHashIndex_Load(t *testing.T) {
+	usdt := tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe")
+	fake := tongo.MustParseAccountID("0:ca9006bd3fb03d355daeeff93b24be90afaa6e3ca0073ff5720f8a852c933278")
+	hash := ImageHash{Kind: DifferenceHash, Hash: 0xff00ff00ff00ff00}
+
+	index := NewImageHashIndex(WithHammingThreshold(DifferenceHash, 2))
+	require.Nil(t, index.Load([]byte(`{"images": [{"asset": "jetton", "name": "Tether USD", "address": "0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe", "hashes": ["dhash:ff00ff00ff00ff00"]}]}`), false))
+	require.Equal(t, []ProtectedImage{{Asset: JettonImage, Address: usdt, Name: "Tether USD", Hashes: []ImageHash{hash}}}, index.Images())
+
+	match, found := index.Match(fake, []ImageHash{{Kind: DifferenceHash, Hash: 0xff00ff00ff00ff03}})
+	require.True(t, found)
+	require.Equal(t, 2, match.Distance)
+	_, found = index.Match(fake, []ImageHash{{Kind: DifferenceHash, Hash: 0xff00ff00ff00ff07}})
+	require.False(t, found)
+	_, found = index.Match(usdt, []ImageHash{hash})
+	require.False(t, found)
+
+	require.Nil(t, index.Load([]byte(`
+images:
+  - asset: collection
+    name: TON Diamonds
+    address: "0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe"
+    hashes: ["dhash:ff00ff00ff00ff00"]
+`), true))
+	require.Equal(t, []ProtectedImage{{Asset: CollectionImage, Address: usdt, Name: "TON Diamonds", Hashes: []ImageHash{hash}}}, index.Images())
+}
+
+func TestComputeImageHashes_TooLarge(t *testing.T) {
+	// a PNG header declaring a 100000x100000 image without pixel data
+	header := []byte("\x89PNG\r\n\x1a\n")
+	ihdr := []byte("IHDR\x00\x01\x86\xa0\x00\x01\x86\xa0\x08\x06\x00\x00\x00")
+	header = binary.BigEndian.AppendUint32(header, uint32(len(ihdr)-4))
+	header = append(header, ihdr...)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+	_, err := ComputeImageHashes(header)
+	require.ErrorIs(t, err, ErrImageTooLarge)
+
+	logo := encodePNG(t, testLogo(256, color.Black, color.White))
+	index := NewImageHashIndex(WithMaxImagePixels(100 * 100))
+	_, _, err = index.CheckImage(tongo.AccountID{}, logo)
+	require.ErrorIs(t, err, ErrImageTooLarge)
+	require.ErrorIs(t, index.AddImage(JettonImage, tongo.AccountID{}, "Logo", logo), ErrImageTooLarge)
+	_, err = ComputeImageHashes(logo)
+	require.Nil(t, err)
+}
+
+func TestGrayThumbnail_Sampling(t *testing.T) {
+	small := testLogo(64, color.Black, color.White)
+	large := image.NewRGBA(image.Rect(0, 0, 2048, 2048))
+	for y := 0; y < 2048; y++ {
+		for x := 0; x < 2048; x++ {
+			large.Set(x, y, small.At(x/32, y/32))
+		}
+	}
+	for _, kind := range imageHashes {
+		expected, err := HashImage(small, kind)
+		require.Nil(t, err)
+		hash, err := HashImage(large, kind)
+		require.Nil(t, err)
+		distance, err := hash.Distance(expected)
+		require.Nil(t, err)
+		require.LessOrEqual(t, distance, defaultHammingThresholds[kind], kind)
+	}
+}
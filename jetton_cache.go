@@ -0,0 +1,70 @@
+package scam_backoffice_rules
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// jettonCache is the on-disk copy of the last successfully downloaded list of well-known jettons.
+type jettonCache struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Checksum is a hex encoded sha256 of Jettons.
+	Checksum string          `json:"checksum"`
+	Jettons  json.RawMessage `json:"jettons"`
+}
+
+func readJettonCache(path string) ([]Jetton, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var cache jettonCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, time.Time{}, err
+	}
+	checksum := sha256.Sum256(cache.Jettons)
+	if hex.EncodeToString(checksum[:]) != cache.Checksum {
+		return nil, time.Time{}, fmt.Errorf("checksum mismatch")
+	}
+	jettons, err := decodeJettons(bytes.NewReader(cache.Jettons))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return jettons, cache.Timestamp, nil
+}
+
+// writeJettonCache replaces the cache atomically, so a crash never leaves a half-written file.
+func writeJettonCache(path string, jettons []Jetton, timestamp time.Time) error {
+	raw, err := json.Marshal(jettons)
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(raw)
+	data, err := json.Marshal(jettonCache{
+		Timestamp: timestamp,
+		Checksum:  hex.EncodeToString(checksum[:]),
+		Jettons:   raw,
+	})
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
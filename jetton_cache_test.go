@@ -0,0 +1,56 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestJettonCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jettons.cache")
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.Nil(t, writeJettonCache(path, testKnownJettons, timestamp))
+
+	jettons, cachedAt, err := readJettonCache(path)
+	require.Nil(t, err)
+	require.Equal(t, testKnownJettons, jettons)
+	require.True(t, timestamp.Equal(cachedAt))
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	data[len(data)/2] ^= 1
+	require.Nil(t, os.WriteFile(path, data, 0o600))
+	_, _, err = readJettonCache(path)
+	require.NotNil(t, err)
+}
+
+func TestJettonVerifier_Cache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jettons.cache")
+
+	verifier := NewJettonVerifier(WithJettonSources(StaticJettonSource(testKnownJettons)), WithCachePath(path))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, verifier.WaitReady(ctx))
+	verifier.Close()
+
+	// the source is down, but the cache is loaded synchronously
+	verifier = NewJettonVerifier(WithJettonSources(failingJettonSource{}), WithCachePath(path))
+	defer verifier.Close()
+	select {
+	case <-verifier.Ready():
+	default:
+		t.Fatal("verifier must be ready after loading the cache")
+	}
+	require.Equal(t, len(testKnownJettons), verifier.Status().Jettons)
+	require.True(t, verifier.IsBlacklisted(ton.AccountID{}, "jUSDT"))
+
+	require.Nil(t, os.WriteFile(path, []byte("{corrupted"), 0o600))
+	corrupted := NewJettonVerifier(WithJettonSources(failingJettonSource{}), WithCachePath(path))
+	defer corrupted.Close()
+	require.Equal(t, 0, corrupted.Status().Jettons)
+}
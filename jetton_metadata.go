@@ -0,0 +1,194 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tonkeeper/tongo"
+)
+
+// minDescriptionBrandLength is the minimum length of a normalized name or symbol of a well-known jetton
+// looked for in descriptions, otherwise "NOT" would be found in every other text.
+const minDescriptionBrandLength = 5
+
+// DefaultJettonBrands are looked for in descriptions of jettons when no other list is configured.
+// Names and symbols of well-known jettons are looked for too.
+var DefaultJettonBrands = []string{
+	"Tether",
+	"USDT",
+	"USDC",
+	"Toncoin",
+	"Tonkeeper",
+	"Notcoin",
+	"Binance",
+}
+
+// WithBrands replaces DefaultJettonBrands looked for in descriptions of jettons.
+func WithBrands(brands ...string) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.brands = normalizeBrands(brands)
+	}
+}
+
+func normalizeBrands(brands []string) []string {
+	normalized := make([]string, 0, len(brands))
+	for _, brand := range brands {
+		if brand = NormalizeString(brand); brand != "" {
+			normalized = append(normalized, brand)
+		}
+	}
+	return normalized
+}
+
+// imageKey is an image URL used to compare images of jettons.
+func imageKey(image string) string {
+	return strings.TrimSuffix(strings.TrimSpace(image), "/")
+}
+
+// JettonMetadata is metadata of a jetton master in the TEP-64 format.
+type JettonMetadata struct {
+	Address     tongo.AccountID `json:"address"`
+	Name        string          `json:"name"`
+	Symbol      string          `json:"symbol"`
+	Description string          `json:"description"`
+	Image       string          `json:"image"`
+	// Decimals are nil if the metadata doesn't specify them, then they are 9 according to TEP-64.
+	Decimals *int `json:"decimals,omitempty"`
+}
+
+// defaultJettonDecimals are decimals of a jetton without decimals in its metadata.
+const defaultJettonDecimals = 9
+
+// decimals returns the decimals of the jetton taking the TEP-64 default into account.
+func (m JettonMetadata) decimals() int {
+	if m.Decimals == nil {
+		return defaultJettonDecimals
+	}
+	return *m.Decimals
+}
+
+// JettonMetadataVerdict collects verdicts of all checks of jetton metadata.
+// The zero value means the jetton looks fine.
+type JettonMetadataVerdict struct {
+	Verdicts []JettonVerdict `json:"verdicts,omitempty"`
+}
+
+// Blacklisted returns true if the jetton should not be trusted.
+func (v JettonMetadataVerdict) Blacklisted() bool {
+	return len(v.Verdicts) > 0
+}
+
+// Severity returns the highest severity of all verdicts, it is empty if the jetton looks fine.
+func (v JettonMetadataVerdict) Severity() TypeOfSeverity {
+	var severity TypeOfSeverity
+	for _, verdict := range v.Verdicts {
+		if verdict.Severity == SeverityCritical {
+			return SeverityCritical
+		}
+		severity = verdict.Severity
+	}
+	return severity
+}
+
+// Reasons returns reasons of all verdicts in the order the checks run.
+func (v JettonMetadataVerdict) Reasons() []TypeOfJettonReason {
+	reasons := make([]TypeOfJettonReason, 0, len(v.Verdicts))
+	for _, verdict := range v.Verdicts {
+		reasons = append(reasons, verdict.Reason)
+	}
+	return reasons
+}
+
+// CheckJettonMetadata runs CheckJetton and additionally checks that the jetton doesn't copy
+// an image of a well-known jetton, doesn't mention well-known brands in its description
+// and has the same decimals as the jetton it imitates. Every failed check is a separate verdict.
+// Well-known jettons are never reported.
+func (verifier *JettonVerifier) CheckJettonMetadata(metadata JettonMetadata) JettonMetadataVerdict {
+	return verifier.CheckJettonMetadataContext(context.Background(), metadata)
+}
+
+// CheckJettonMetadataContext is CheckJettonMetadata with a context for the reputation store.
+func (verifier *JettonVerifier) CheckJettonMetadataContext(ctx context.Context, metadata JettonMetadata) JettonMetadataVerdict {
+	if verdict, ok := verifier.reputationVerdict(ctx, metadata.Address); ok {
+		if verdict.Blacklisted() {
+			return JettonMetadataVerdict{Verdicts: []JettonVerdict{verdict}}
+		}
+		return JettonMetadataVerdict{}
+	}
+	if verifier.isWellKnown(metadata.Address) {
+		// the image and the description of a well-known jetton are the originals
+		return JettonMetadataVerdict{}
+	}
+	var result JettonMetadataVerdict
+	var impersonated *Jetton
+	verdict := verifier.CheckJettonContext(ctx, metadata.Address, metadata.Name, metadata.Symbol)
+	if verdict.Blacklisted() {
+		result.Verdicts = append(result.Verdicts, verdict)
+		impersonated = verdict.Impersonated
+	}
+
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+	if jettons, ok := verifier.images[imageKey(metadata.Image)]; ok {
+		copied := anyJetton(jettons)
+		verdict := newJettonVerdict(CopiedImage)
+		verdict.Field = ImageField
+		verdict.Impersonated = &copied
+		result.Verdicts = append(result.Verdicts, verdict)
+		if impersonated == nil {
+			impersonated = &copied
+		}
+	}
+	if brand, jetton, ok := verifier.mentionedBrand(metadata.Description); ok {
+		verdict := newJettonVerdict(DescriptionMentionsBrand)
+		verdict.Field = DescriptionField
+		verdict.Symbol = brand
+		verdict.Impersonated = jetton
+		result.Verdicts = append(result.Verdicts, verdict)
+		if impersonated == nil {
+			impersonated = jetton
+		}
+	}
+	if impersonated != nil && impersonated.Decimals != nil && *impersonated.Decimals != metadata.decimals() {
+		verdict := newJettonVerdict(DecimalsMismatch)
+		verdict.Field = DecimalsField
+		verdict.Impersonated = impersonated
+		result.Verdicts = append(result.Verdicts, verdict)
+	}
+	return result
+}
+
+// mentionedBrand returns the longest name or symbol of a well-known jetton mentioned in the description,
+// or the first configured brand if there is none. It must be called with mu held.
+func (verifier *JettonVerifier) mentionedBrand(description string) (string, *Jetton, bool) {
+	normalized := NormalizeString(description)
+	if normalized == "" {
+		return "", nil, false
+	}
+	var mentioned []string
+	for brand := range verifier.descriptionBrands {
+		if strings.Contains(normalized, brand) {
+			mentioned = append(mentioned, brand)
+		}
+	}
+	if len(mentioned) > 0 {
+		// longer brands are more specific, "tetherusd" is better than "tether"
+		sort.Slice(mentioned, func(i, j int) bool {
+			li, lj := utf8.RuneCountInString(mentioned[i]), utf8.RuneCountInString(mentioned[j])
+			if li != lj {
+				return li > lj
+			}
+			return mentioned[i] < mentioned[j]
+		})
+		jetton := anyJetton(verifier.descriptionBrands[mentioned[0]])
+		return mentioned[0], &jetton, true
+	}
+	for _, brand := range verifier.brands {
+		if strings.Contains(normalized, brand) {
+			return brand, nil, true
+		}
+	}
+	return "", nil, false
+}
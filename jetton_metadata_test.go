@@ -0,0 +1,96 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestJettonVerifier_CheckJettonMetadata(t *testing.T) {
+	six, nine := 6, 9
+	usdt := Jetton{
+		Name:        "Tether USD",
+		Symbol:      "USD₮",
+		Address:     tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe"),
+		Decimals:    &six,
+		Image:       "https://tether.to/images/logoCircle.png",
+		Description: "Tether Token for Tether USD",
+	}
+	fake := tongo.MustParseAccountID("0:ca9006bd3fb03d355daeeff93b24be90afaa6e3ca0073ff5720f8a852c933278")
+	tests := []struct {
+		name        string
+		metadata    JettonMetadata
+		wantReasons []TypeOfJettonReason
+		severity    TypeOfSeverity
+	}{
+		{
+			name: "original",
+			metadata: JettonMetadata{Address: usdt.Address, Name: usdt.Name, Symbol: usdt.Symbol,
+				Description: usdt.Description, Image: usdt.Image, Decimals: &six},
+			wantReasons: []TypeOfJettonReason{},
+		},
+		{
+			name:        "unrelated",
+			metadata:    JettonMetadata{Address: fake, Name: "Kitty", Symbol: "KIT", Description: "Meow"},
+			wantReasons: []TypeOfJettonReason{},
+		},
+		{
+			name: "full copy with other decimals",
+			metadata: JettonMetadata{Address: fake, Name: usdt.Name, Symbol: usdt.Symbol,
+				Description: usdt.Description, Image: usdt.Image + "/", Decimals: &nine},
+			wantReasons: []TypeOfJettonReason{ImpersonatesJetton, CopiedImage, DescriptionMentionsBrand, DecimalsMismatch},
+			severity:    SeverityCritical,
+		},
+		{
+			name: "copied image",
+			metadata: JettonMetadata{Address: fake, Name: "Kitty", Symbol: "KIT",
+				Image: usdt.Image, Decimals: &six},
+			wantReasons: []TypeOfJettonReason{CopiedImage},
+			severity:    SeverityCritical,
+		},
+		{
+			name:        "copied image without decimals",
+			metadata:    JettonMetadata{Address: fake, Name: "Kitty", Symbol: "KIT", Image: usdt.Image},
+			wantReasons: []TypeOfJettonReason{CopiedImage, DecimalsMismatch},
+			severity:    SeverityCritical,
+		},
+		{
+			name:        "description with lookalikes",
+			metadata:    JettonMetadata{Address: fake, Name: "Kitty", Symbol: "KIT", Description: "Backed 1:1 by Теther USD", Decimals: &nine},
+			wantReasons: []TypeOfJettonReason{DescriptionMentionsBrand, DecimalsMismatch},
+			severity:    SeverityWarning,
+		},
+		{
+			name:        "configured brand",
+			metadata:    JettonMetadata{Address: fake, Name: "Kitty", Symbol: "KIT", Description: "Listed on Binance soon", Decimals: &nine},
+			wantReasons: []TypeOfJettonReason{DescriptionMentionsBrand},
+			severity:    SeverityWarning,
+		},
+	}
+	verifier := &JettonVerifier{brands: normalizeBrands(DefaultJettonBrands)}
+	verifier.updateJettons([]Jetton{usdt})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := verifier.CheckJettonMetadata(tt.metadata)
+			require.Equal(t, tt.wantReasons, verdict.Reasons())
+			require.Equal(t, tt.severity, verdict.Severity())
+			for _, v := range verdict.Verdicts {
+				if v.Reason != DescriptionMentionsBrand || v.Symbol != "binance" {
+					require.Equal(t, usdt.Address, v.Impersonated.Address)
+				}
+			}
+		})
+	}
+}
+
+func TestWithBrands(t *testing.T) {
+	verifier := &JettonVerifier{}
+	WithBrands("Getgems", "")(verifier)
+	require.Equal(t, []string{"getgems"}, verifier.brands)
+
+	verdict := verifier.CheckJettonMetadata(JettonMetadata{Name: "Gem", Symbol: "GEM", Description: "by GetGems team"})
+	require.Equal(t, []TypeOfJettonReason{DescriptionMentionsBrand}, verdict.Reasons())
+	require.Equal(t, DescriptionField, verdict.Verdicts[0].Field)
+	require.Equal(t, "getgems", verdict.Verdicts[0].Symbol)
+}
@@ -0,0 +1,151 @@
+package scam_backoffice_rules
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tonkeeper/tongo"
+)
+
+type TypeOfSimilarity string
+
+const (
+	// EditDistance means the symbols are within the Damerau-Levenshtein distance allowed for their length.
+	EditDistance TypeOfSimilarity = "edit_distance"
+	// Transposition means the symbols differ by a swap of two adjacent characters, like "USTD".
+	Transposition TypeOfSimilarity = "transposition"
+	// Padding means a well-known symbol is prefixed or suffixed with a few characters, like "USDT1",
+	// one character at the other end may be dropped, like "jUSD".
+	Padding TypeOfSimilarity = "padding"
+)
+
+// SimilarityConfig configures fuzzy comparison of normalized symbols with well-known ones.
+type SimilarityConfig struct {
+	EditDistance  bool
+	Transposition bool
+	Padding       bool
+	// MaxPadding is the maximum number of characters added to a well-known symbol.
+	MaxPadding int
+	// MinPaddedLength is the minimum length of a well-known symbol to look for its padded versions,
+	// otherwise "NOTE" would imitate "NOT".
+	MinPaddedLength int
+	// MaxDistance returns the edit distance allowed for a well-known symbol of the given length.
+	MaxDistance func(length int) int
+}
+
+// DefaultSimilarityConfig enables all checks with thresholds growing with the symbol length.
+func DefaultSimilarityConfig() SimilarityConfig {
+	return SimilarityConfig{
+		EditDistance:    true,
+		Transposition:   true,
+		Padding:         true,
+		MaxPadding:      2,
+		MinPaddedLength: 4,
+		MaxDistance: func(length int) int {
+			switch {
+			case length <= 3:
+				return 0
+			case length <= 5:
+				return 1
+			default:
+				return 2
+			}
+		},
+	}
+}
+
+// WithSimilarity makes IsBlacklisted report symbols similar to well-known ones,
+// not only equal to them after normalization.
+func WithSimilarity(config SimilarityConfig) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.similarity = &config
+	}
+}
+
+// SimilarJetton is the closest well-known jetton to a symbol.
+type SimilarJetton struct {
+	Jetton     Jetton           `json:"jetton"`
+	Similarity TypeOfSimilarity `json:"similarity"`
+	// Distance is the Damerau-Levenshtein distance between normalized symbols.
+	Distance int `json:"distance"`
+}
+
+// ClosestJetton returns the well-known jetton with a symbol most similar to the given one.
+// Nothing is returned if similarity checks are not configured with WithSimilarity,
+// if the symbol equals a well-known one after normalization
+// or if the address itself belongs to a well-known jetton.
+func (verifier *JettonVerifier) ClosestJetton(address tongo.AccountID, symbol string) (SimilarJetton, bool) {
+	symbol = NormalizeString(symbol)
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+	return verifier.closestJetton(address, symbol)
+}
+
+// closestJetton must be called with mu held.
+func (verifier *JettonVerifier) closestJetton(address tongo.AccountID, normalized string) (SimilarJetton, bool) {
+	if verifier.similarity == nil || normalized == "" {
+		return SimilarJetton{}, false
+	}
+	if _, ok := verifier.jettons[normalized]; ok {
+		return SimilarJetton{}, false
+	}
+	if _, ok := verifier.addresses[address]; ok {
+		return SimilarJetton{}, false
+	}
+	config := verifier.similarity
+	best, bestKnown, found := SimilarJetton{}, "", false
+	for known, jettons := range verifier.jettons {
+		if known == "" {
+			continue
+		}
+		similarity, distance, ok := compareSymbols(config, normalized, known)
+		if !ok {
+			continue
+		}
+		// ties are broken by the normalized symbol and then by the address (see anyJetton),
+		// so the result doesn't depend on map ordering
+		if found && (distance > best.Distance || distance == best.Distance && known > bestKnown) {
+			continue
+		}
+		best, bestKnown, found = SimilarJetton{Jetton: anyJetton(jettons), Similarity: similarity, Distance: distance}, known, true
+	}
+	return best, found
+}
+
+func compareSymbols(config *SimilarityConfig, symbol, known string) (TypeOfSimilarity, int, bool) {
+	distance := damerauLevenshtein(symbol, known)
+	length := utf8.RuneCountInString(known)
+	if config.Transposition && isTransposition(symbol, known) {
+		return Transposition, distance, true
+	}
+	if config.Padding && length >= config.MinPaddedLength && distance <= config.MaxPadding && isPadded(symbol, known) {
+		return Padding, distance, true
+	}
+	if config.EditDistance && config.MaxDistance != nil && distance <= config.MaxDistance(length) {
+		return EditDistance, distance, true
+	}
+	return "", 0, false
+}
+
+// isPadded returns true if symbol is known with characters added at one end,
+// the last character of known may be dropped when characters are added before it and vice versa.
+func isPadded(symbol, known string) bool {
+	if strings.HasPrefix(symbol, known) || strings.HasSuffix(symbol, known) {
+		return true
+	}
+	runes := []rune(known)
+	head, tail := string(runes[:len(runes)-1]), string(runes[1:])
+	return (strings.HasSuffix(symbol, head) && symbol != head) || (strings.HasPrefix(symbol, tail) && symbol != tail)
+}
+
+// anyJetton returns the jetton with the smallest address, so the result doesn't depend on map ordering.
+func anyJetton(jettons map[tongo.AccountID]Jetton) Jetton {
+	var result Jetton
+	first := true
+	for address, jetton := range jettons {
+		if first || address.ToRaw() < result.Address.ToRaw() {
+			result, first = jetton, false
+		}
+	}
+	return result
+}
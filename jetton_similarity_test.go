@@ -0,0 +1,122 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestJettonVerifier_ClosestJetton(t *testing.T) {
+	usdt := Jetton{
+		Name:    "Tether USD",
+		Symbol:  "USD₮",
+		Address: tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe"),
+	}
+	tests := []struct {
+		symbol         string
+		address        ton.AccountID
+		wantSymbol     string
+		wantSimilarity TypeOfSimilarity
+		wantDistance   int
+		wantFound      bool
+	}{
+		{symbol: "USDTT", wantSymbol: "USD₮", wantSimilarity: Padding, wantDistance: 1, wantFound: true},
+		{symbol: "USDT1", wantSymbol: "USD₮", wantSimilarity: Padding, wantDistance: 1, wantFound: true},
+		{symbol: "USTD", wantSymbol: "USD₮", wantSimilarity: Transposition, wantDistance: 1, wantFound: true},
+		{symbol: "jUSD", wantSymbol: "jUSDT", wantSimilarity: EditDistance, wantDistance: 1, wantFound: true},
+		{symbol: "AMBRA", wantSymbol: "AMBR", wantSimilarity: Padding, wantDistance: 1, wantFound: true},
+		{symbol: "CFTT", wantFound: false},
+		{symbol: "BTC", wantFound: false},
+		{symbol: "USDT", wantFound: false},
+		{symbol: "USDTT", address: usdt.Address, wantFound: false},
+	}
+	verifier := &JettonVerifier{}
+	WithSimilarity(DefaultSimilarityConfig())(verifier)
+	verifier.updateJettons(append([]Jetton{usdt}, testKnownJettons...))
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			similar, found := verifier.ClosestJetton(tt.address, tt.symbol)
+			require.Equal(t, tt.wantFound, found)
+			if !found {
+				return
+			}
+			require.Equal(t, tt.wantSymbol, similar.Jetton.Symbol)
+			require.Equal(t, tt.wantSimilarity, similar.Similarity)
+			require.Equal(t, tt.wantDistance, similar.Distance)
+			require.True(t, verifier.IsBlacklisted(tt.address, tt.symbol))
+		})
+	}
+}
+
+func TestJettonVerifier_ClosestJetton_onlyUSDT(t *testing.T) {
+	usdt := Jetton{
+		Name:    "Tether USD",
+		Symbol:  "USD₮",
+		Address: tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe"),
+	}
+	verifier := &JettonVerifier{}
+	WithSimilarity(DefaultSimilarityConfig())(verifier)
+	verifier.updateJettons([]Jetton{usdt})
+
+	for _, symbol := range []string{"jUSD", "xUSDT", "USDT1", "USTD", "SDTx"} {
+		similar, found := verifier.ClosestJetton(ton.AccountID{}, symbol)
+		require.True(t, found, symbol)
+		require.Equal(t, usdt.Address, similar.Jetton.Address, symbol)
+	}
+	similar, _ := verifier.ClosestJetton(ton.AccountID{}, "jUSD")
+	require.Equal(t, Padding, similar.Similarity)
+	require.Equal(t, 2, similar.Distance)
+	require.True(t, verifier.IsBlacklisted(ton.AccountID{}, "jUSD"))
+
+	for _, symbol := range []string{"BTC", "TON", "NOTUSD"} {
+		_, found := verifier.ClosestJetton(ton.AccountID{}, symbol)
+		require.False(t, found, symbol)
+	}
+}
+
+func TestJettonVerifier_SimilarityDisabled(t *testing.T) {
+	verifier := &JettonVerifier{}
+	verifier.updateJettons(testKnownJettons)
+	_, found := verifier.ClosestJetton(ton.AccountID{}, "jUSD")
+	require.False(t, found)
+	require.False(t, verifier.IsBlacklisted(ton.AccountID{}, "jUSD"))
+}
+
+func TestJettonVerifier_ClosestJetton_ties(t *testing.T) {
+	jettons := []Jetton{
+		{Symbol: "USDE", Address: testAddress(3)},
+		{Symbol: "USDC", Address: testAddress(2)},
+		{Symbol: "USDC", Address: testAddress(1)},
+	}
+	verifier := &JettonVerifier{}
+	WithSimilarity(DefaultSimilarityConfig())(verifier)
+	for i := 0; i < 20; i++ {
+		verifier.updateJettons(jettons)
+		similar, found := verifier.ClosestJetton(ton.AccountID{}, "USDX")
+		require.True(t, found)
+		require.Equal(t, "USDC", similar.Jetton.Symbol)
+		require.Equal(t, testAddress(1), similar.Jetton.Address)
+	}
+}
+
+func TestDamerauLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "USDT", b: "", want: 4},
+		{a: "", b: "USDT", want: 4},
+		{a: "USDT", b: "USDT", want: 0},
+		{a: "USDT", b: "USTD", want: 1},
+		{a: "USDT", b: "USD₮", want: 1},
+		{a: "jUSDT", b: "USDT", want: 1},
+		{a: "CA", b: "ABC", want: 3},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, damerauLevenshtein(tt.a, tt.b), "%q %q", tt.a, tt.b)
+	}
+}
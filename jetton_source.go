@@ -0,0 +1,221 @@
+package scam_backoffice_rules
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+//go:embed jettons_snapshot.json
+var jettonsSnapshot []byte
+
+// JettonSource provides a list of well-known jettons to JettonVerifier.
+type JettonSource interface {
+	Jettons(ctx context.Context) ([]Jetton, error)
+}
+
+// ErrNotModified is returned by a source when the list hasn't changed since the previous call.
+var ErrNotModified = errors.New("not modified")
+
+const (
+	defaultHTTPUserAgent = "scam_backoffice_rules"
+	defaultHTTPMaxSize   = 32 << 20
+	defaultHTTPTimeout   = 30 * time.Second
+)
+
+// httpSource downloads a list with conditional requests, it is shared by HTTP sources of jettons and collections.
+type httpSource struct {
+	URL       string
+	Client    *http.Client
+	UserAgent string
+	// MaxSize limits the size of a response body in bytes.
+	MaxSize int64
+
+	// mu protects etag and lastModified
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+}
+
+// HTTPJettonSource downloads well-known jettons in the ton-assets format.
+// It sends conditional requests and returns ErrNotModified when the server answers 304.
+type HTTPJettonSource struct {
+	httpSource
+}
+
+// HTTPSourceOption configures HTTPJettonSource and HTTPNftCollectionSource.
+type HTTPSourceOption func(s *httpSource)
+
+// WithHTTPClient configures a client used to download lists,
+// by default a client with a 30 seconds timeout is used.
+func WithHTTPClient(client *http.Client) HTTPSourceOption {
+	return func(s *httpSource) {
+		s.Client = client
+	}
+}
+
+func WithUserAgent(userAgent string) HTTPSourceOption {
+	return func(s *httpSource) {
+		s.UserAgent = userAgent
+	}
+}
+
+// WithMaxSize limits the size of a response body in bytes, 32 MiB by default.
+func WithMaxSize(size int64) HTTPSourceOption {
+	return func(s *httpSource) {
+		s.MaxSize = size
+	}
+}
+
+// FileJettonSource reads well-known jettons in the ton-assets format from a local file.
+type FileJettonSource struct {
+	Path string
+}
+
+// EmbeddedJettonSource returns a snapshot of the ton-assets list embedded into the binary.
+// It is useful in air-gapped environments and as a fallback for other sources.
+// The snapshot is updated with "make update-jettons-snapshot", the repository only carries
+// a few major jettons until the target is run, so run it before building for an air-gapped environment.
+type EmbeddedJettonSource struct{}
+
+// StaticJettonSource is a fixed in-memory list of well-known jettons.
+type StaticJettonSource []Jetton
+
+func NewHTTPJettonSource(url string, opts ...HTTPSourceOption) *HTTPJettonSource {
+	source := &HTTPJettonSource{}
+	source.init(url, opts)
+	return source
+}
+
+func (s *httpSource) init(url string, opts []HTTPSourceOption) {
+	s.URL = url
+	s.Client = &http.Client{Timeout: defaultHTTPTimeout}
+	s.UserAgent = defaultHTTPUserAgent
+	s.MaxSize = defaultHTTPMaxSize
+	for _, o := range opts {
+		o(s)
+	}
+}
+
+func NewFileJettonSource(path string) *FileJettonSource {
+	return &FileJettonSource{Path: path}
+}
+
+func (s *HTTPJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	data, validators, err := s.download(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jettons, err := decodeJettons(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s.setValidators(validators)
+	return jettons, nil
+}
+
+// cacheValidators are response headers used to send conditional requests.
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+// download sends a conditional request and returns the response body with its validators,
+// they should be saved with setValidators once the body is decoded.
+func (s *httpSource) download(ctx context.Context) ([]byte, cacheValidators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, cacheValidators{}, err
+	}
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+	s.mu.Lock()
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.lastModified != "" {
+		req.Header.Set("If-Modified-Since", s.lastModified)
+	}
+	s.mu.Unlock()
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, cacheValidators{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cacheValidators{}, ErrNotModified
+	}
+	if resp.StatusCode >= 300 {
+		return nil, cacheValidators{}, fmt.Errorf("invalid status code %v", resp.StatusCode)
+	}
+	body := io.Reader(resp.Body)
+	if s.MaxSize > 0 {
+		if resp.ContentLength > s.MaxSize {
+			return nil, cacheValidators{}, fmt.Errorf("response is too large: %v bytes", resp.ContentLength)
+		}
+		body = io.LimitReader(resp.Body, s.MaxSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, cacheValidators{}, err
+	}
+	if s.MaxSize > 0 && int64(len(data)) > s.MaxSize {
+		return nil, cacheValidators{}, fmt.Errorf("response is larger than %v bytes", s.MaxSize)
+	}
+	return data, cacheValidators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+func (s *httpSource) setValidators(validators cacheValidators) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag = validators.etag
+	s.lastModified = validators.lastModified
+}
+
+func (s *httpSource) resetValidators() {
+	s.setValidators(cacheValidators{})
+}
+
+func (s *FileJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeJettons(file)
+}
+
+func (EmbeddedJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	return decodeJettons(bytes.NewReader(jettonsSnapshot))
+}
+
+func (s StaticJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	return append([]Jetton(nil), s...), nil
+}
+
+func decodeJettons(r io.Reader) ([]Jetton, error) {
+	var jettons []Jetton
+	if err := json.NewDecoder(r).Decode(&jettons); err != nil {
+		return nil, err
+	}
+	return jettons, nil
+}
+
+// fetchJettons returns jettons of the first source which succeeds.
+func fetchJettons(ctx context.Context, sources []JettonSource) ([]Jetton, error) {
+	return fetchFirst(ctx, sources, JettonSource.Jettons, "jettons")
+}
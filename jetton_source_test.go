@@ -0,0 +1,153 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingJettonSource struct{}
+
+func (failingJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestJettonSources(t *testing.T) {
+	body := `[{"name":"jUSDT","symbol":"jUSDT","address":"0:729c13b6df2c07cbf0a06ab63d34af454f3d320ec1bcd8fb5c6d24d0806a17c2"}]`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "jettons.json")
+	require.Nil(t, os.WriteFile(path, []byte(body), 0o600))
+
+	sources := map[string]JettonSource{
+		"http":   NewHTTPJettonSource(server.URL),
+		"file":   NewFileJettonSource(path),
+		"static": StaticJettonSource(testKnownJettons[1:2]),
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			jettons, err := source.Jettons(context.Background())
+			require.Nil(t, err)
+			require.Len(t, jettons, 1)
+			require.Equal(t, testKnownJettons[1].Address, jettons[0].Address)
+		})
+	}
+
+	jettons, err := EmbeddedJettonSource{}.Jettons(context.Background())
+	require.Nil(t, err)
+	require.NotEmpty(t, jettons)
+}
+
+func TestFetchJettons_Fallback(t *testing.T) {
+	jettons, err := fetchJettons(context.Background(), []JettonSource{failingJettonSource{}, StaticJettonSource(testKnownJettons)})
+	require.Nil(t, err)
+	require.Equal(t, testKnownJettons, jettons)
+
+	_, err = fetchJettons(context.Background(), []JettonSource{failingJettonSource{}})
+	require.NotNil(t, err)
+}
+
+func TestHTTPJettonSource_Conditional(t *testing.T) {
+	body := `[{"name":"jUSDT","symbol":"jUSDT","address":"0:729c13b6df2c07cbf0a06ab63d34af454f3d320ec1bcd8fb5c6d24d0806a17c2"}]`
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	source := NewHTTPJettonSource(server.URL, WithHTTPClient(server.Client()), WithUserAgent("test-agent"))
+	verifier := &JettonVerifier{sources: []JettonSource{source}}
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.Equal(t, 1, verifier.Status().Jettons)
+	firstRefresh := verifier.Status().LastRefresh
+
+	_, err := source.Jettons(context.Background())
+	require.ErrorIs(t, err, ErrNotModified)
+
+	require.Nil(t, verifier.Refresh(context.Background()))
+	status := verifier.Status()
+	require.Equal(t, 1, status.Jettons)
+	require.Nil(t, status.LastError)
+	require.False(t, status.LastRefresh.Before(firstRefresh))
+	require.Equal(t, 3, requests)
+}
+
+func TestHTTPJettonSource_NotModifiedAfterFallback(t *testing.T) {
+	body := `[{"name":"jUSDT","symbol":"jUSDT","address":"0:729c13b6df2c07cbf0a06ab63d34af454f3d320ec1bcd8fb5c6d24d0806a17c2"}]`
+	var mu sync.Mutex
+	failing, conditional := false, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	setFailing := func(value bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		failing = value
+	}
+
+	source := NewHTTPJettonSource(server.URL, WithHTTPClient(server.Client()))
+	verifier := &JettonVerifier{sources: []JettonSource{source, StaticJettonSource(testKnownJettons)}}
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.Equal(t, 1, verifier.Status().Jettons)
+
+	setFailing(true)
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.Equal(t, len(testKnownJettons), verifier.Status().Jettons)
+
+	// the fallback list is installed, so the server must send the whole list again instead of 304
+	setFailing(false)
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.Equal(t, 1, verifier.Status().Jettons)
+	require.Nil(t, verifier.Refresh(context.Background()))
+	require.Equal(t, 1, verifier.Status().Jettons)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, conditional)
+}
+
+func TestHTTPJettonSource_MaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // unknown content length
+		_, _ = w.Write([]byte(`[` + strings.Repeat(" ", 1024) + `]`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPJettonSource(server.URL, WithMaxSize(100)).Jettons(context.Background())
+	require.NotNil(t, err)
+
+	jettons, err := NewHTTPJettonSource(server.URL).Jettons(context.Background())
+	require.Nil(t, err)
+	require.Empty(t, jettons)
+}
@@ -0,0 +1,99 @@
+package scam_backoffice_rules
+
+import "fmt"
+
+// TypeOfJettonReason explains why a jetton is blacklisted.
+type TypeOfJettonReason string
+
+const (
+	// NonGraphicCharacter means the field contains an invisible or control character.
+	NonGraphicCharacter TypeOfJettonReason = "non_graphic_character"
+	// DisallowedCharacter means the field contains a character not allowed by the charset, see WithCharset.
+	DisallowedCharacter TypeOfJettonReason = "disallowed_character"
+	// MixedScripts means the field mixes letters of a script allowed only in whole symbols with other letters.
+	MixedScripts TypeOfJettonReason = "mixed_scripts"
+	// BlacklistedSymbol means the normalized field is one of the blacklisted symbols.
+	BlacklistedSymbol TypeOfJettonReason = "blacklisted_symbol"
+	// ImpersonatesJetton means the normalized field equals the one of a well-known jetton at another address.
+	ImpersonatesJetton TypeOfJettonReason = "impersonates_jetton"
+	// SimilarToJetton means the normalized symbol is close to the one of a well-known jetton, see WithSimilarity.
+	SimilarToJetton TypeOfJettonReason = "similar_to_jetton"
+	// UnverifiedJetton means the list of well-known jettons is stale and FailClosed policy is configured.
+	UnverifiedJetton TypeOfJettonReason = "unverified_jetton"
+	// CopiedImage means the image URL is the one of a well-known jetton at another address.
+	CopiedImage TypeOfJettonReason = "copied_image"
+	// DescriptionMentionsBrand means the description mentions a well-known brand, see WithBrands.
+	DescriptionMentionsBrand TypeOfJettonReason = "description_mentions_brand"
+	// DecimalsMismatch means the decimals differ from the ones of the impersonated jetton,
+	// so amounts in a wallet look different from what the user expects.
+	DecimalsMismatch TypeOfJettonReason = "decimals_mismatch"
+	// ReportedScam means the jetton master has the scam reputation, see WithReputation.
+	ReportedScam TypeOfJettonReason = "reported_scam"
+)
+
+// TypeOfSeverity helps the backoffice to choose how loud a warning should be.
+type TypeOfSeverity string
+
+const (
+	// SeverityWarning is for suspicious jettons, like ones with unusual characters.
+	SeverityWarning TypeOfSeverity = "warning"
+	// SeverityCritical is for jettons impersonating well-known ones.
+	SeverityCritical TypeOfSeverity = "critical"
+)
+
+var reasonSeverity = map[TypeOfJettonReason]TypeOfSeverity{
+	NonGraphicCharacter: SeverityWarning,
+	DisallowedCharacter: SeverityWarning,
+	MixedScripts:        SeverityWarning,
+	BlacklistedSymbol:   SeverityCritical,
+	ImpersonatesJetton:  SeverityCritical,
+	SimilarToJetton:     SeverityWarning,
+	UnverifiedJetton:    SeverityWarning,
+	CopiedImage:         SeverityCritical,
+	// legitimate jettons mention USDT in descriptions too, like "swap to USDT"
+	DescriptionMentionsBrand: SeverityWarning,
+	DecimalsMismatch:         SeverityWarning,
+	ReportedScam:             SeverityCritical,
+}
+
+// JettonVerdict is the result of a jetton check. The zero value means the jetton looks fine.
+type JettonVerdict struct {
+	Reason   TypeOfJettonReason `json:"reason,omitempty"`
+	Severity TypeOfSeverity     `json:"severity,omitempty"`
+	// Field is the jetton field which triggered the verdict.
+	Field TypeOfJettonField `json:"field,omitempty"`
+	// Rune is the offending character for NonGraphicCharacter, DisallowedCharacter and MixedScripts.
+	Rune rune `json:"rune,omitempty"`
+	// Symbol is the normalized value matched with a blacklisted or a well-known one.
+	Symbol string `json:"symbol,omitempty"`
+	// Impersonated is the well-known jetton imitated by the checked one.
+	Impersonated *Jetton `json:"impersonated,omitempty"`
+	// Distance is the edit distance to the impersonated jetton for SimilarToJetton.
+	Distance int `json:"distance,omitempty"`
+	// Reputation is the reputation of the jetton master for ReportedScam.
+	Reputation *Reputation `json:"reputation,omitempty"`
+}
+
+func newJettonVerdict(reason TypeOfJettonReason) JettonVerdict {
+	return JettonVerdict{Reason: reason, Severity: reasonSeverity[reason]}
+}
+
+// Blacklisted returns true if the jetton should not be trusted.
+func (v JettonVerdict) Blacklisted() bool {
+	return v.Reason != ""
+}
+
+func (v JettonVerdict) String() string {
+	switch {
+	case !v.Blacklisted():
+		return "ok"
+	case v.Impersonated != nil:
+		return fmt.Sprintf("%v %v: impersonates %v (%v)", v.Field, v.Reason, v.Impersonated.Symbol, v.Impersonated.Address.ToRaw())
+	case v.Reputation != nil:
+		return fmt.Sprintf("%v: %v", v.Reason, v.Reputation.Source)
+	case v.Rune != 0:
+		return fmt.Sprintf("%v %v: %q (%U)", v.Field, v.Reason, v.Rune, v.Rune)
+	default:
+		return fmt.Sprintf("%v %v: %v", v.Field, v.Reason, v.Symbol)
+	}
+}
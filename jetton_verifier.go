@@ -1,16 +1,13 @@
 package scam_backoffice_rules
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
+	"context"
+	"os"
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
-	"golang.org/x/exp/slices"
-
-	"github.com/avast/retry-go"
 	"github.com/labstack/gommon/log"
 	"github.com/tonkeeper/tongo"
 )
@@ -21,19 +18,135 @@ const jettonPath = "https://raw.githubusercontent.com/tonkeeper/ton-assets/main/
 // The list of well-known jettons is maintained by the community and can be found there:
 // https://raw.githubusercontent.com/tonkeeper/ton-assets/main/jettons.json
 type JettonVerifier struct {
-	// mu protects Jettons
+	// mu protects Jettons and their indexes, the refresh status is kept by refreshLoop
 	mu      sync.RWMutex
-	jettons map[string]map[tongo.AccountID]jetton
+	jettons map[string]map[tongo.AccountID]Jetton
+	// names contains well-known jettons indexed by their normalized names.
+	names map[string]map[tongo.AccountID]Jetton
+	// addresses contains addresses of all well-known jettons.
+	addresses map[tongo.AccountID]struct{}
+	// similarity enables fuzzy matching of symbols, it is nil by default.
+	similarity *SimilarityConfig
+
+	failPolicy TypeOfFailPolicy
+	// cachePath is a file where the last successfully downloaded list is kept between restarts.
+	cachePath string
+	// sources are tried in order until one of them returns the list of well-known jettons.
+	sources []JettonSource
+	// blacklist is defaultBlacklist unless configured with WithBlacklist.
+	blacklist       *Blacklist
+	blacklistSource BlacklistSource
+	// charset is defaultCharset unless configured with WithCharset.
+	charset *Charset
+	// images contains well-known jettons indexed by their image URLs.
+	images map[string]map[tongo.AccountID]Jetton
+	// brands are normalized brands looked for in descriptions, see WithBrands.
+	brands []string
+	// descriptionBrands contains well-known jettons indexed by their normalized names and symbols
+	// long enough to be looked for in descriptions.
+	descriptionBrands map[string]map[tongo.AccountID]Jetton
+	// reputation is consulted before any other check, see WithReputation.
+	reputation ReputationStore
+
+	refreshLoop
+}
+
+type JettonVerifierOption func(v *JettonVerifier)
+
+// TypeOfFailPolicy defines how JettonVerifier treats symbols it doesn't know
+// while the list of well-known jettons is missing or stale.
+type TypeOfFailPolicy string
+
+const (
+	// FailOpen allows unknown symbols, it is the default policy.
+	FailOpen TypeOfFailPolicy = "fail_open"
+	// FailClosed treats unknown symbols as suspicious.
+	FailClosed TypeOfFailPolicy = "fail_closed"
+)
+
+// JettonVerifierStatus describes the state of the list of well-known jettons.
+type JettonVerifierStatus struct {
+	// LastRefresh is the time of the last successful refresh, it is zero if the list has never been loaded.
+	LastRefresh time.Time
+	LastAttempt time.Time
+	// LastError is the error of the last refresh attempt, it is nil if the attempt succeeded.
+	LastError error
+	Jettons   int
+	// Stale is true if the list is missing or older than the configured max age.
+	Stale bool
+}
+
+// WithJettonSources configures where the list of well-known jettons comes from.
+// Sources are tried in order, so a remote source can be followed by a local fallback:
+//
+//	NewJettonVerifier(WithJettonSources(NewHTTPJettonSource(mirrorURL), EmbeddedJettonSource{}))
+//
+// By default, the list is downloaded from the ton-assets repository.
+func WithJettonSources(sources ...JettonSource) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.sources = sources
+	}
+}
+
+// WithRefreshInterval configures how often the list of well-known jettons is refreshed, one hour by default.
+// A random delay up to jitter is added to every interval. A non-positive interval is ignored.
+func WithRefreshInterval(interval, jitter time.Duration) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.setInterval(interval, jitter)
+	}
+}
+
+// WithFailPolicy configures how unknown symbols are treated while the list of well-known jettons
+// has never been loaded or is older than maxAge. Zero maxAge means the list never gets stale.
+func WithFailPolicy(policy TypeOfFailPolicy, maxAge time.Duration) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.failPolicy = policy
+		v.maxAge = maxAge
+	}
+}
+
+// WithCachePath keeps the last successfully downloaded list of well-known jettons in a file.
+// The file is loaded synchronously by the constructor, so the verifier is ready right after a restart.
+// A missing or corrupted file is ignored.
+func WithCachePath(path string) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.cachePath = path
+	}
+}
+
+// WithBlacklist replaces the default list of symbols known to be used by scam jettons.
+// Entries are normalized with NormalizeString, entries starting with "re:" are regular expressions
+// matched against normalized symbols.
+// If source is not nil, the blacklist is reloaded from it on every refresh.
+func WithBlacklist(blacklist *Blacklist, source BlacklistSource) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.blacklist = blacklist
+		v.blacklistSource = source
+	}
+}
+
+// WithReputation makes the verifier trust jetton masters with the trusted or exchange reputation
+// and report ones with the scam reputation, see ReportedScam.
+func WithReputation(store ReputationStore) JettonVerifierOption {
+	return func(v *JettonVerifier) {
+		v.reputation = store
+	}
 }
 
-type jetton struct {
+type Jetton struct {
 	Name    string          `json:"name"`
 	Address tongo.AccountID `json:"address"`
 	Symbol  string          `json:"symbol"`
+	// Decimals is nil if the list doesn't specify them.
+	Decimals    *int   `json:"decimals,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
+// defaultBlacklist is used by verifiers without their own blacklist.
+var defaultBlacklist = mustBlacklist(hardcodedBlacklistedSymbols)
+
 // hardcodedBlacklistedSymbols contains symbols that are known to be used by scam jettons.
-var hardcodedBlacklistedSymbolsMutex sync.RWMutex
 var hardcodedBlacklistedSymbols = []string{
 	"ton",
 	"$ton",
@@ -68,160 +181,298 @@ var hardcodedBlacklistedSymbols = []string{
 	"u$dtether",
 }
 
-// allowedRanges specifies what unicode characters are safe to be used in jetton symbols.
-// Unicode is so powerful that it is easy to trick a human into thinking that a scam jetton is a well-known one.
-// So blacklisting is kind of challenging.
-//
-// An ideal jetton should probably have a plain-English symbol, like "jUSDT".
-//
-// If you feel that some unicode range should be added to this list,
-// please create an issue or open a request.
-var allowedRanges = []*unicode.RangeTable{ //ordered by popularity
-	unicode.Latin,
-	unicode.ASCII_Hex_Digit,
-	unicode.Space,
-	unicode.Mark,
-	unicode.Dash,
-	simplePunct,
-	unicode.Cyrillic,
-	manuallyWhitelisted,
-	unicode.Hyphen,
-	unicode.Telugu,
-	unicode.Devanagari,
-	unicode.Katakana,
-}
-
-// simplePunct contains basic ASCII punctuation allowed in jetton symbols.
-var simplePunct = &unicode.RangeTable{
-	R16: []unicode.Range16{
-		{0x0021, 0x0023, 1},  // ! " #
-		{0x0025, 0x002a, 1},  // % & ' ( ) *
-		{0x002c, 0x002f, 1},  // , - . /
-		{0x003a, 0x003b, 1},  // : ;
-		{0x003f, 0x0040, 1},  // ? @
-		{0x005b, 0x005d, 1},  // [ \ ]
-		{0x005f, 0x007b, 28}, // _ {
-		{0x007d, 0x00a1, 36}, // } ¡
-	},
-}
-
-var manuallyWhitelisted = &unicode.RangeTable{
-	R16: []unicode.Range16{
-		{34, 34, 1},     // "
-		{36, 36, 1},     // $
-		{39, 39, 1},     // '
-		{43, 43, 1},     // +
-		{61, 61, 1},     // =
-		{96, 96, 1},     // `
-		{126, 126, 1},   // ~
-		{8216, 8217, 1}, // ''
-		{8220, 8221, 1}, // ""
-		{8366, 8366, 1}, // ₮
-	},
-	R32: []unicode.Range32{
-		{0x2764, 0x2764, 1},   // ❤
-		{0x4eba, 0x4eba, 1},   // 人
-		{0x56fd, 0x56fd, 1},   // 国
-		{0x5e01, 0x5e01, 1},   // 币
-		{0x9fb1, 0x9fb1, 1},   // 龱
-		{0x1f48e, 0x1f48e, 1}, // 💎
-
-	},
-}
-
-func NewJettonVerifier() *JettonVerifier {
-	verifier := JettonVerifier{
+// NewJettonVerifier is NewJettonVerifierContext with a background context.
+func NewJettonVerifier(opts ...JettonVerifierOption) *JettonVerifier {
+	return NewJettonVerifierContext(context.Background(), opts...)
+}
+
+// NewJettonVerifierContext returns a verifier which refreshes the list of well-known jettons in background
+// until the context is canceled or Close is called.
+// The list is empty until the first refresh succeeds, use Ready or WaitReady to wait for it.
+func NewJettonVerifierContext(ctx context.Context, opts ...JettonVerifierOption) *JettonVerifier {
+	verifier := &JettonVerifier{
 		// we have valid jettons sharing the same symbol
-		jettons: map[string]map[tongo.AccountID]jetton{},
+		jettons:     map[string]map[tongo.AccountID]Jetton{},
+		sources:     []JettonSource{NewHTTPJettonSource(jettonPath)},
+		blacklist:   defaultBlacklist,
+		charset:     defaultCharset,
+		brands:      normalizeBrands(DefaultJettonBrands),
+		refreshLoop: newRefreshLoop(),
+	}
+	for _, o := range opts {
+		o(verifier)
 	}
-	go verifier.run()
-	return &verifier
+	if verifier.cachePath != "" {
+		verifier.loadCache()
+	}
+	verifier.start(ctx, verifier.Refresh)
+	return verifier
 }
 
-func (verifier *JettonVerifier) run() {
-	for {
-		_ = retry.Do(func() error {
-			knownJettons, err := downloadJettons()
-			if err != nil {
-				log.Errorf("failed to download jettons: %v", err)
-				return err
+// Refresh downloads the list of well-known jettons right away.
+func (verifier *JettonVerifier) Refresh(ctx context.Context) error {
+	verifier.refreshMu.Lock()
+	defer verifier.refreshMu.Unlock()
+	if verifier.blacklistSource != nil && verifier.blacklist != nil {
+		if err := verifier.reloadBlacklist(ctx); err != nil {
+			// an outdated blacklist is better than no list of well-known jettons
+			log.Errorf("failed to reload blacklist: %v", err)
+		}
+	}
+	fetch := func(ctx context.Context) ([]Jetton, error) {
+		return fetchJettons(ctx, verifier.sources)
+	}
+	return refreshList(ctx, &verifier.refreshLoop, fetch, func(knownJettons []Jetton) {
+		verifier.updateJettons(knownJettons)
+		if verifier.cachePath != "" {
+			if err := writeJettonCache(verifier.cachePath, knownJettons, time.Now()); err != nil {
+				log.Errorf("failed to write jettons cache: %v", err)
 			}
-			verifier.updateJettons(knownJettons)
-			return nil
-		}, retry.Attempts(3), retry.Delay(5*time.Second))
+		}
+	})
+}
+
+func (verifier *JettonVerifier) reloadBlacklist(ctx context.Context) error {
+	entries, err := verifier.blacklistSource.Blacklist(ctx)
+	if err != nil {
+		return err
+	}
+	return verifier.blacklist.Set(entries)
+}
 
-		time.Sleep(time.Hour * 1)
+func (verifier *JettonVerifier) loadCache() {
+	knownJettons, timestamp, err := readJettonCache(verifier.cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed to read jettons cache: %v", err)
+		}
+		return
 	}
+	verifier.setJettons(knownJettons, timestamp)
+	verifier.markReady()
 }
 
-func (verifier *JettonVerifier) updateJettons(knownJettons []jetton) {
-	jettons := make(map[string]map[tongo.AccountID]jetton, len(knownJettons))
+func (verifier *JettonVerifier) updateJettons(knownJettons []Jetton) {
+	verifier.setJettons(knownJettons, time.Now())
+}
+
+// setJettons replaces the list of well-known jettons downloaded at the given time.
+func (verifier *JettonVerifier) setJettons(knownJettons []Jetton, timestamp time.Time) {
+	jettons := make(map[string]map[tongo.AccountID]Jetton, len(knownJettons))
+	names := make(map[string]map[tongo.AccountID]Jetton, len(knownJettons))
+	addresses := make(map[tongo.AccountID]struct{}, len(knownJettons))
+	images := map[string]map[tongo.AccountID]Jetton{}
+	descriptionBrands := map[string]map[tongo.AccountID]Jetton{}
 	for _, item := range knownJettons {
-		normalized := NormalizeString(item.Symbol)
-		if _, ok := jettons[normalized]; !ok {
-			jettons[normalized] = make(map[tongo.AccountID]jetton)
+		addresses[item.Address] = struct{}{}
+		symbol := NormalizeString(item.Symbol)
+		indexJetton(jettons, symbol, item)
+		name := NormalizeString(item.Name)
+		if name != "" {
+			indexJetton(names, name, item)
+		}
+		if image := imageKey(item.Image); image != "" {
+			indexJetton(images, image, item)
+		}
+		for _, brand := range []string{symbol, name} {
+			if utf8.RuneCountInString(brand) >= minDescriptionBrandLength {
+				indexJetton(descriptionBrands, brand, item)
+			}
 		}
-		jettons[normalized][item.Address] = item
 	}
 	verifier.mu.Lock()
 	defer verifier.mu.Unlock()
 	verifier.jettons = jettons
+	verifier.names = names
+	verifier.addresses = addresses
+	verifier.images = images
+	verifier.descriptionBrands = descriptionBrands
+	verifier.setRefreshed(timestamp, len(knownJettons))
+}
+
+func indexJetton(index map[string]map[tongo.AccountID]Jetton, key string, item Jetton) {
+	if _, ok := index[key]; !ok {
+		index[key] = make(map[tongo.AccountID]Jetton)
+	}
+	index[key][item.Address] = item
+}
+
+// Status returns the state of the list of well-known jettons.
+func (verifier *JettonVerifier) Status() JettonVerifierStatus {
+	status := verifier.status()
+	return JettonVerifierStatus{
+		LastRefresh: status.LastRefresh,
+		LastAttempt: status.LastAttempt,
+		LastError:   status.LastError,
+		Jettons:     status.Count,
+		Stale:       status.Stale,
+	}
 }
 
 // IsBlacklisted returns true if the jetton SYMBOL is similar to any of the well-known jettons.
 func (verifier *JettonVerifier) IsBlacklisted(address tongo.AccountID, symbol string) bool {
+	return verifier.VerifySymbol(address, symbol).Blacklisted()
+}
+
+// VerifySymbol explains why the jetton SYMBOL is similar to any of the well-known jettons.
+func (verifier *JettonVerifier) VerifySymbol(address tongo.AccountID, symbol string) JettonVerdict {
+	verdict := verifier.verifyValue(address, symbol)
+	if verdict.Blacklisted() {
+		verdict.Field = SymbolField
+	}
+	return verdict
+}
+
+func (verifier *JettonVerifier) verifyValue(address tongo.AccountID, symbol string) JettonVerdict {
 	for _, s := range symbol {
 		// if the symbol contains non-printable characters,
 		// we consider it a scam.
 		if !unicode.IsGraphic(s) {
-			return true
+			verdict := newJettonVerdict(NonGraphicCharacter)
+			verdict.Rune = s
+			return verdict
 		}
 	}
-	for _, s := range symbol {
-		if !unicode.In(s, allowedRanges...) {
-			return true
-		}
+	charset := verifier.charset
+	if charset == nil {
+		charset = defaultCharset
 	}
-	symbol = NormalizeString(symbol)
-	hardcodedBlacklistedSymbolsMutex.RLock()
-	copyHardcodedBlacklistedSymbols := hardcodedBlacklistedSymbols
-	hardcodedBlacklistedSymbolsMutex.RUnlock()
-	if slices.Contains(copyHardcodedBlacklistedSymbols, symbol) {
-		return true
+	if s, reason, ok := charset.Check(symbol); !ok {
+		verdict := newJettonVerdict(reason)
+		verdict.Rune = s
+		return verdict
 	}
+	symbol = NormalizeString(symbol)
 	verifier.mu.RLock()
 	defer verifier.mu.RUnlock()
 
 	jettons, ok := verifier.jettons[symbol]
+	if _, original := jettons[address]; ok && original {
+		// this jetton is in our list of well-known jettons,
+		// the blacklist may contain its symbol to catch fakes
+		return JettonVerdict{}
+	}
+	blacklist := verifier.blacklist
+	if blacklist == nil {
+		blacklist = defaultBlacklist
+	}
+	if entry, ok := blacklist.Match(symbol); ok {
+		verdict := newJettonVerdict(BlacklistedSymbol)
+		verdict.Symbol = entry
+		return verdict
+	}
 	if !ok {
-		// no jettons with such symbol
-		return false
+		if similar, ok := verifier.closestJetton(address, symbol); ok {
+			verdict := newJettonVerdict(SimilarToJetton)
+			verdict.Symbol = symbol
+			verdict.Impersonated = &similar.Jetton
+			verdict.Distance = similar.Distance
+			return verdict
+		}
+		// no jettons with such symbol,
+		// but we can't be sure about it if our list is outdated
+		if verifier.failPolicy == FailClosed && verifier.isStale() {
+			verdict := newJettonVerdict(UnverifiedJetton)
+			verdict.Symbol = symbol
+			return verdict
+		}
+		return JettonVerdict{}
 	}
+	return impersonationVerdict(jettons, address, symbol)
+}
+
+// impersonationVerdict checks if the address is one of the well-known jettons sharing the same normalized value.
+func impersonationVerdict(jettons map[tongo.AccountID]Jetton, address tongo.AccountID, symbol string) JettonVerdict {
 	if _, ok := jettons[address]; ok {
 		// this jetton is in our list of well-known jettons
-		return false
+		return JettonVerdict{}
 	}
-	return true
+	impersonated := anyJetton(jettons)
+	verdict := newJettonVerdict(ImpersonatesJetton)
+	verdict.Symbol = symbol
+	verdict.Impersonated = &impersonated
+	return verdict
 }
 
-func SetBlacklistedSymbols(blacklistedSymbols []string) {
-	hardcodedBlacklistedSymbolsMutex.Lock()
-	hardcodedBlacklistedSymbols = blacklistedSymbols
-	hardcodedBlacklistedSymbolsMutex.Unlock()
+type TypeOfJettonField string
+
+const (
+	SymbolField      TypeOfJettonField = "symbol"
+	NameField        TypeOfJettonField = "name"
+	DescriptionField TypeOfJettonField = "description"
+	ImageField       TypeOfJettonField = "image"
+	DecimalsField    TypeOfJettonField = "decimals"
+)
+
+// CheckJetton returns a verdict for the jetton if its symbol or name imitates any of the well-known jettons.
+// The symbol goes through the same checks as IsBlacklisted,
+// the name is compared with names of well-known jettons,
+// so "Tether USD" is caught even with an unrelated symbol.
+// Well-known jettons are never reported.
+func (verifier *JettonVerifier) CheckJetton(address tongo.AccountID, name, symbol string) JettonVerdict {
+	return verifier.CheckJettonContext(context.Background(), address, name, symbol)
 }
 
-func downloadJettons() ([]jetton, error) {
-	resp, err := http.Get(jettonPath)
-	if err != nil {
-		return nil, err
+// CheckJettonContext is CheckJetton with a context for the reputation store.
+func (verifier *JettonVerifier) CheckJettonContext(ctx context.Context, address tongo.AccountID, name, symbol string) JettonVerdict {
+	if verdict, ok := verifier.reputationVerdict(ctx, address); ok {
+		return verdict
+	}
+	if verifier.isWellKnown(address) {
+		return JettonVerdict{}
+	}
+	if verdict := verifier.VerifySymbol(address, symbol); verdict.Blacklisted() {
+		return verdict
+	}
+	if name == "" {
+		return JettonVerdict{}
+	}
+	normalized := NormalizeString(name)
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+	jettons, ok := verifier.names[normalized]
+	if !ok {
+		return JettonVerdict{}
+	}
+	verdict := impersonationVerdict(jettons, address, normalized)
+	verdict.Field = NameField
+	return verdict
+}
+
+// isWellKnown returns true if the address is one of the well-known jettons.
+func (verifier *JettonVerifier) isWellKnown(address tongo.AccountID) bool {
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+	_, ok := verifier.addresses[address]
+	return ok
+}
+
+// reputationVerdict returns a verdict for a jetton master with a known reputation.
+// ok is false if the reputation doesn't decide, then other checks run.
+func (verifier *JettonVerifier) reputationVerdict(ctx context.Context, address tongo.AccountID) (JettonVerdict, bool) {
+	if verifier.reputation == nil {
+		return JettonVerdict{}, false
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("invalid status code %v", resp.StatusCode)
+	reputation, ok := lookupReputation(ctx, verifier.reputation, address)
+	if !ok {
+		return JettonVerdict{}, false
+	}
+	switch reputation.Label {
+	case TrustedReputation, ExchangeReputation:
+		return JettonVerdict{}, true
+	case ScamReputation:
+		verdict := newJettonVerdict(ReportedScam)
+		verdict.Reputation = &reputation
+		return verdict, true
 	}
-	var jettons []jetton
-	if err = json.NewDecoder(resp.Body).Decode(&jettons); err != nil {
-		return nil, err
+	return JettonVerdict{}, false
+}
+
+// SetBlacklistedSymbols replaces the blacklist of all verifiers created without WithBlacklist.
+//
+// Deprecated: use WithBlacklist to configure a blacklist of a particular verifier.
+func SetBlacklistedSymbols(blacklistedSymbols []string) {
+	if err := defaultBlacklist.Set(blacklistedSymbols); err != nil {
+		log.Errorf("failed to set blacklisted symbols: %v", err)
 	}
-	return jettons, nil
 }
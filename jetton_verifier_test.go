@@ -1,8 +1,13 @@
 package scam_backoffice_rules
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tonkeeper/tongo"
@@ -10,7 +15,7 @@ import (
 )
 
 var (
-	testKnownJettons = []jetton{
+	testKnownJettons = []Jetton{
 		{
 			Name:    "Cock Fights Token",
 			Symbol:  "CFT",
@@ -237,12 +242,109 @@ func TestJettonVerifier_IsSimilarToWellKnownSymbol(t *testing.T) {
 	}
 }
 
+func TestJettonVerifier_CheckJetton(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenName string
+		symbol    string
+		address   ton.AccountID
+		wantField TypeOfJettonField
+		wantFound bool
+	}{
+		{name: "original", tokenName: "Ambra", symbol: "AMBR", address: testKnownJettons[3].Address},
+		{name: "unrelated", tokenName: "My Token", symbol: "MYT"},
+		{name: "fake symbol", tokenName: "My Token", symbol: "AMBR", wantField: SymbolField, wantFound: true},
+		{name: "fake name", tokenName: "Tether USD", symbol: "TUSD", wantField: NameField, wantFound: true},
+		{name: "fake name with lookalikes", tokenName: "Теther USD", symbol: "TUSD", wantField: NameField, wantFound: true},
+		{name: "name is a well-known symbol", tokenName: "jUSDT", symbol: "JT", wantField: NameField, wantFound: true},
+		{name: "name is not checked against the symbol blacklist", tokenName: "$USDT", symbol: "TUSD"},
+	}
+	verifier := &JettonVerifier{}
+	verifier.updateJettons(testKnownJettons)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := verifier.CheckJetton(tt.address, tt.tokenName, tt.symbol)
+			require.Equal(t, tt.wantFound, verdict.Blacklisted())
+			require.Equal(t, tt.wantField, verdict.Field)
+		})
+	}
+}
+
+func TestJettonVerifier_CheckJetton_wellKnown(t *testing.T) {
+	usdt := ton.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe")
+	notcoin := ton.MustParseAccountID("0:2f956143c461769579baef2e32cc2d7bc18283f40d20bb03e432cd603ac33ffc")
+	verifier := &JettonVerifier{}
+	verifier.updateJettons([]Jetton{
+		{Name: "Tether USD", Symbol: "USD₮", Address: usdt},
+		{Name: "Notcoin", Symbol: "NOT", Address: notcoin},
+	})
+	require.False(t, verifier.CheckJetton(usdt, "Tether USD", "USD₮").Blacklisted())
+	require.False(t, verifier.CheckJetton(notcoin, "Notcoin", "NOT").Blacklisted())
+
+	verdict := verifier.CheckJetton(ton.AccountID{}, "Tether USD", "USD₮")
+	require.Equal(t, ImpersonatesJetton, verdict.Reason)
+	require.Equal(t, SymbolField, verdict.Field)
+	verdict = verifier.CheckJetton(ton.AccountID{}, "Notcoin", "NOTC")
+	require.True(t, verdict.Blacklisted())
+	require.Equal(t, NameField, verdict.Field)
+}
+
+func TestJettonVerifier_VerifySymbol(t *testing.T) {
+	tests := []struct {
+		name             string
+		symbol           string
+		address          ton.AccountID
+		wantReason       TypeOfJettonReason
+		wantSeverity     TypeOfSeverity
+		wantRune         rune
+		wantImpersonated string
+	}{
+		{name: "ok", symbol: "Random Symbol"},
+		{name: "original", symbol: "jUSDT", address: testKnownJettons[1].Address},
+		{name: "non graphic", symbol: "jU\u2063SDT", wantReason: NonGraphicCharacter, wantSeverity: SeverityWarning, wantRune: 0x2063},
+		{name: "disallowed", symbol: "USDب", wantReason: DisallowedCharacter, wantSeverity: SeverityWarning, wantRune: 'ب'},
+		{name: "blacklisted", symbol: "$TON", wantReason: BlacklistedSymbol, wantSeverity: SeverityCritical},
+		{name: "impersonation", symbol: "AMBR", wantReason: ImpersonatesJetton, wantSeverity: SeverityCritical, wantImpersonated: "Ambra"},
+	}
+	verifier := &JettonVerifier{}
+	verifier.updateJettons(testKnownJettons)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := verifier.VerifySymbol(tt.address, tt.symbol)
+			require.Equal(t, tt.wantReason, verdict.Reason)
+			require.Equal(t, tt.wantSeverity, verdict.Severity)
+			require.Equal(t, tt.wantRune, verdict.Rune)
+			if tt.wantImpersonated == "" {
+				require.Nil(t, verdict.Impersonated)
+				return
+			}
+			require.Equal(t, tt.wantImpersonated, verdict.Impersonated.Name)
+			require.Equal(t, SymbolField, verdict.Field)
+		})
+	}
+}
+
 func TestJettonVerifier_run(t *testing.T) {
+	knownJettons, err := EmbeddedJettonSource{}.Jettons(context.Background())
+	require.Nil(t, err)
+	requireNoEmptySymbols(t, knownJettons)
+}
+
+// TestJettonVerifier_runFullList checks the whole ton-assets list, the embedded snapshot may be outdated.
+func TestJettonVerifier_runFullList(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	knownJettons, err := NewHTTPJettonSource(jettonPath).Jettons(ctx)
+	if err != nil {
+		t.Skipf("ton-assets is not available: %v", err)
+	}
+	requireNoEmptySymbols(t, knownJettons)
+}
+
+func requireNoEmptySymbols(t *testing.T, knownJettons []Jetton) {
 	verifier := &JettonVerifier{
-		jettons: map[string]map[tongo.AccountID]jetton{},
+		jettons: map[string]map[tongo.AccountID]Jetton{},
 	}
-	knownJettons, err := downloadJettons()
-	require.Nil(t, err)
 	verifier.updateJettons(knownJettons)
 
 	jettons := verifier.jettons[""]
@@ -251,3 +353,108 @@ func TestJettonVerifier_run(t *testing.T) {
 	}
 	require.Equal(t, 0, len(jettons))
 }
+
+type countingJettonSource struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *countingJettonSource) Jettons(ctx context.Context) ([]Jetton, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return testKnownJettons, nil
+}
+
+func TestJettonVerifier_Lifecycle(t *testing.T) {
+	source := &countingJettonSource{}
+	verifier := NewJettonVerifierContext(context.Background(),
+		WithJettonSources(source),
+		WithRefreshInterval(time.Hour, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, verifier.WaitReady(ctx))
+	require.True(t, verifier.IsBlacklisted(ton.AccountID{}, "jUSDT"))
+
+	require.Nil(t, verifier.Refresh(ctx))
+	source.mu.Lock()
+	require.Equal(t, 2, source.calls)
+	source.mu.Unlock()
+
+	verifier.Close()
+	verifier.Close()
+}
+
+func TestJettonVerifier_WaitReadyTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	verifier := NewJettonVerifierContext(ctx, WithJettonSources(failingJettonSource{}))
+	defer verifier.Close()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	require.ErrorIs(t, verifier.WaitReady(waitCtx), context.DeadlineExceeded)
+
+	cancel()
+	select {
+	case <-verifier.Ready():
+		t.Fatal("verifier must not be ready")
+	default:
+	}
+}
+
+func TestJettonVerifier_ReadyOnNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+	verifier := NewJettonVerifier(WithJettonSources(NewHTTPJettonSource(server.URL)))
+	defer verifier.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, verifier.WaitReady(ctx))
+	require.Nil(t, verifier.Status().LastError)
+}
+
+func TestJettonVerifier_FailPolicy(t *testing.T) {
+	verifier := &JettonVerifier{}
+	require.True(t, verifier.Status().Stale)
+	require.False(t, verifier.IsBlacklisted(ton.AccountID{}, "Random Symbol"))
+
+	verifier = &JettonVerifier{failPolicy: FailClosed}
+	verifier.maxAge = time.Hour
+	require.True(t, verifier.IsBlacklisted(ton.AccountID{}, "Random Symbol"))
+
+	verifier.updateJettons(testKnownJettons)
+	status := verifier.Status()
+	require.False(t, status.Stale)
+	require.Equal(t, len(testKnownJettons), status.Jettons)
+	require.False(t, verifier.IsBlacklisted(ton.AccountID{}, "Random Symbol"))
+
+	verifier.lastRefresh = time.Now().Add(-2 * time.Hour)
+	require.True(t, verifier.Status().Stale)
+	require.True(t, verifier.IsBlacklisted(ton.AccountID{}, "Random Symbol"))
+	require.False(t, verifier.IsBlacklisted(testKnownJettons[0].Address, testKnownJettons[0].Symbol))
+}
+
+func TestJettonVerifier_StatusError(t *testing.T) {
+	verifier := &JettonVerifier{sources: []JettonSource{failingJettonSource{}}}
+	require.NotNil(t, verifier.Refresh(context.Background()))
+	status := verifier.Status()
+	require.NotNil(t, status.LastError)
+	require.False(t, status.LastAttempt.IsZero())
+	require.True(t, status.LastRefresh.IsZero())
+}
+
+func TestWithRefreshInterval(t *testing.T) {
+	verifier := &JettonVerifier{refreshLoop: newRefreshLoop()}
+	WithRefreshInterval(0, 0)(verifier)
+	require.Equal(t, time.Hour, verifier.refreshInterval)
+	WithRefreshInterval(-time.Minute, -time.Second)(verifier)
+	require.Equal(t, time.Hour, verifier.refreshInterval)
+	require.Equal(t, time.Duration(0), verifier.refreshJitter)
+	WithRefreshInterval(time.Minute, time.Second)(verifier)
+	require.Equal(t, time.Minute, verifier.refreshInterval)
+	require.Equal(t, time.Second, verifier.refreshJitter)
+}
@@ -0,0 +1,125 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lists contains named lists of values referenced by rules, like a list of phishing domains.
+// A list can be replaced at any moment, rules always use its latest version,
+// so a phishing domain can be blocked without reloading rules.
+type Lists struct {
+	// mu protects lists
+	mu    sync.RWMutex
+	lists map[string]*valueList
+}
+
+// ConvertedLists is the yaml/json representation of Lists.
+type ConvertedLists struct {
+	Lists map[string][]string `yaml:"lists" json:"lists"`
+}
+
+type listKind string
+
+const (
+	domainList listKind = "domain"
+	// domainSkeletonList contains skeletons of domains, see DomainSkeleton.
+	domainSkeletonList listKind = "domain_skeleton"
+	tldList            listKind = "tld"
+)
+
+// canonical converts a list value to the form used by a matcher.
+func (kind listKind) canonical(value string) string {
+	switch kind {
+	case domainList:
+		return CanonicalDomain(value)
+	case domainSkeletonList:
+		return DomainSkeleton(CanonicalDomain(value))
+	case tldList:
+		return CanonicalDomain(strings.TrimPrefix(strings.TrimSpace(value), "."))
+	}
+	return value
+}
+
+type valueList struct {
+	values []string
+	// mu protects indexes
+	mu sync.Mutex
+	// indexes contains canonical values, they are built on the first lookup.
+	indexes map[listKind]map[string]struct{}
+}
+
+func NewLists() *Lists {
+	return &Lists{lists: map[string]*valueList{}}
+}
+
+// Set replaces the content of the list with the given name.
+func (l *Lists) Set(name string, values []string) {
+	list := &valueList{
+		values:  append([]string(nil), values...),
+		indexes: map[listKind]map[string]struct{}{},
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lists[name] = list
+}
+
+// Get returns the content of the list with the given name.
+func (l *Lists) Get(name string) []string {
+	list := l.get(name)
+	if list == nil {
+		return nil
+	}
+	return append([]string(nil), list.values...)
+}
+
+// Load replaces all lists mentioned in the given yaml or json document.
+func (l *Lists) Load(bytesOfLists []byte, yamlConverted bool) error {
+	var convertedLists ConvertedLists
+	var err error
+	if yamlConverted {
+		err = yaml.Unmarshal(bytesOfLists, &convertedLists)
+	} else {
+		err = json.Unmarshal(bytesOfLists, &convertedLists)
+	}
+	if err != nil {
+		return err
+	}
+	for name, values := range convertedLists.Lists {
+		l.Set(name, values)
+	}
+	return nil
+}
+
+func (l *Lists) get(name string) *valueList {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lists[name]
+}
+
+// contains checks if the canonical value is in the list with the given name.
+func (l *Lists) contains(name string, kind listKind, value string) bool {
+	list := l.get(name)
+	if list == nil {
+		return false
+	}
+	_, ok := list.index(kind)[value]
+	return ok
+}
+
+func (list *valueList) index(kind listKind) map[string]struct{} {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if index, ok := list.indexes[kind]; ok {
+		return index
+	}
+	index := make(map[string]struct{}, len(list.values))
+	for _, value := range list.values {
+		index[kind.canonical(value)] = struct{}{}
+	}
+	list.indexes[kind] = index
+	return index
+}
@@ -0,0 +1,139 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tonkeeper/tongo"
+)
+
+// TypeOfNftField is a field of NFT metadata checked by rules, see the field key of a rule.
+type TypeOfNftField string
+
+const (
+	NftName        TypeOfNftField = "name"
+	NftDescription TypeOfNftField = "description"
+	NftImage       TypeOfNftField = "image"
+	NftExternalURL TypeOfNftField = "external_url"
+	NftAttributes  TypeOfNftField = "attributes"
+	NftCollection  TypeOfNftField = "collection"
+)
+
+func isNftField(field TypeOfNftField) bool {
+	switch field {
+	case NftName, NftDescription, NftImage, NftExternalURL, NftAttributes, NftCollection:
+		return true
+	}
+	return false
+}
+
+// NftAttribute is an attribute of NFT metadata. Numeric values are kept as their JSON text.
+type NftAttribute struct {
+	TraitType string `json:"trait_type"`
+	Value     string `json:"value"`
+}
+
+func (a *NftAttribute) UnmarshalJSON(data []byte) error {
+	var converted struct {
+		TraitType string          `json:"trait_type"`
+		Value     json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return err
+	}
+	a.TraitType = converted.TraitType
+	a.Value = ""
+	if len(converted.Value) == 0 || string(converted.Value) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(converted.Value, &a.Value); err != nil {
+		a.Value = string(converted.Value)
+	}
+	return nil
+}
+
+func (a NftAttribute) String() string {
+	if a.TraitType == "" {
+		return a.Value
+	}
+	return fmt.Sprintf("%v: %v", a.TraitType, a.Value)
+}
+
+// NftMetadata is metadata of an NFT item in the TEP-64 format.
+type NftMetadata struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Image       string         `json:"image"`
+	ExternalURL string         `json:"external_url"`
+	Attributes  []NftAttribute `json:"attributes"`
+	// Collection is the address of the collection of the item, it is not a part of the metadata JSON.
+	Collection *tongo.AccountID `json:"-"`
+}
+
+// NftVerdict is the result of CheckNft.
+type NftVerdict struct {
+	Action TypeOfAction   `json:"action"`
+	Field  TypeOfNftField `json:"field,omitempty"`
+	// Value is the text of the field matched by a rule, a single attribute for NftAttributes.
+	Value string `json:"value,omitempty"`
+}
+
+type nftFieldValue struct {
+	field      TypeOfNftField
+	raw        string
+	normalized string
+	invalid    bool
+}
+
+// fieldValues returns texts of non-empty fields in the order they are checked.
+func (m NftMetadata) fieldValues() []nftFieldValue {
+	var values []nftFieldValue
+	add := func(field TypeOfNftField, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		normalized, err := NormalizeComment(text)
+		values = append(values, nftFieldValue{field: field, raw: text, normalized: normalized, invalid: err != nil})
+	}
+	add(NftName, m.Name)
+	add(NftDescription, m.Description)
+	for _, attribute := range m.Attributes {
+		add(NftAttributes, attribute.String())
+	}
+	add(NftExternalURL, m.ExternalURL)
+	add(NftImage, m.Image)
+	if m.Collection != nil {
+		// an address is not a text, normalization would turn zeros into "o"
+		raw := m.Collection.ToRaw()
+		values = append(values, nftFieldValue{field: NftCollection, raw: raw, normalized: raw})
+	}
+	return values
+}
+
+// CheckNft checks fields of NFT metadata with rules of the nft and all types.
+// A rule with a field checks only that field, other rules check all fields.
+// Like CheckAction, a field with invalid characters is dropped.
+func CheckNft(rules Rules, metadata NftMetadata) NftVerdict {
+	values := metadata.fieldValues()
+	for _, value := range values {
+		if value.invalid {
+			return NftVerdict{Action: Drop, Field: value.field, Value: value.raw}
+		}
+	}
+	for _, rule := range rules {
+		if rule.Type != Nft && rule.Type != All {
+			continue
+		}
+		for _, value := range values {
+			if rule.Field != "" && rule.Field != value.field {
+				continue
+			}
+			action := rule.evaluate(ruleInput{raw: value.raw, normalized: value.normalized, field: value.field})
+			if action != UnKnown {
+				return NftVerdict{Action: action, Field: value.field, Value: value.raw}
+			}
+		}
+	}
+	return NftVerdict{Action: UnKnown}
+}
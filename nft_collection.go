@@ -0,0 +1,284 @@
+package scam_backoffice_rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo"
+)
+
+const collectionsPath = "https://raw.githubusercontent.com/tonkeeper/ton-assets/main/collections.json"
+
+// Collection is a well-known NFT collection in the ton-assets format.
+type Collection struct {
+	Name    string          `json:"name"`
+	Address tongo.AccountID `json:"address"`
+}
+
+// NftCollectionSource provides a list of well-known collections to NftCollectionVerifier.
+type NftCollectionSource interface {
+	Collections(ctx context.Context) ([]Collection, error)
+}
+
+// HTTPNftCollectionSource downloads well-known collections in the ton-assets format.
+// It sends conditional requests like HTTPJettonSource and accepts the same options.
+type HTTPNftCollectionSource struct {
+	httpSource
+}
+
+// FileNftCollectionSource reads well-known collections in the ton-assets format from a local file.
+type FileNftCollectionSource struct {
+	Path string
+}
+
+// StaticNftCollectionSource is a fixed in-memory list of well-known collections.
+type StaticNftCollectionSource []Collection
+
+func NewHTTPNftCollectionSource(url string, opts ...HTTPSourceOption) *HTTPNftCollectionSource {
+	source := &HTTPNftCollectionSource{}
+	source.init(url, opts)
+	return source
+}
+
+func (s *HTTPNftCollectionSource) Collections(ctx context.Context) ([]Collection, error) {
+	data, validators, err := s.download(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := decodeCollections(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s.setValidators(validators)
+	return collections, nil
+}
+
+func (s *FileNftCollectionSource) Collections(ctx context.Context) ([]Collection, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeCollections(file)
+}
+
+func (s StaticNftCollectionSource) Collections(ctx context.Context) ([]Collection, error) {
+	return append([]Collection(nil), s...), nil
+}
+
+func decodeCollections(r io.Reader) ([]Collection, error) {
+	var collections []Collection
+	if err := json.NewDecoder(r).Decode(&collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// fetchCollections returns collections of the first source which succeeds.
+func fetchCollections(ctx context.Context, sources []NftCollectionSource) ([]Collection, error) {
+	return fetchFirst(ctx, sources, NftCollectionSource.Collections, "collections")
+}
+
+// TypeOfCollectionReason explains why a collection is flagged.
+type TypeOfCollectionReason string
+
+const (
+	// ImpersonatesCollection means the normalized name equals the one of a well-known collection at another address.
+	ImpersonatesCollection TypeOfCollectionReason = "impersonates_collection"
+	// SimilarToCollection means the normalized name is close to the one of a well-known collection.
+	SimilarToCollection TypeOfCollectionReason = "similar_to_collection"
+)
+
+// NftCollectionVerdict is the result of a collection check. The zero value means the collection looks fine.
+type NftCollectionVerdict struct {
+	Reason TypeOfCollectionReason `json:"reason,omitempty"`
+	// Name is the normalized name of the checked collection.
+	Name         string      `json:"name,omitempty"`
+	Impersonated *Collection `json:"impersonated,omitempty"`
+	// Distance is the edit distance to the impersonated collection for SimilarToCollection.
+	Distance int `json:"distance,omitempty"`
+}
+
+// Flagged returns true if the collection should not be trusted.
+func (v NftCollectionVerdict) Flagged() bool {
+	return v.Reason != ""
+}
+
+// NftCollectionVerifierStatus describes the state of the list of well-known collections.
+type NftCollectionVerifierStatus struct {
+	// LastRefresh is the time of the last successful refresh, it is zero if the list has never been loaded.
+	LastRefresh time.Time
+	LastAttempt time.Time
+	// LastError is the error of the last refresh attempt, it is nil if the attempt succeeded.
+	LastError   error
+	Collections int
+	// Stale is true if the list is missing or older than the configured max age.
+	Stale bool
+}
+
+// NftCollectionVerifier flags NFT collections imitating well-known ones like "TON Diamonds".
+// It refreshes the list of well-known collections in background like JettonVerifier.
+type NftCollectionVerifier struct {
+	// mu protects collections
+	mu sync.RWMutex
+	// collections contains well-known collections indexed by their normalized names.
+	collections map[string]map[tongo.AccountID]Collection
+	addresses   map[tongo.AccountID]struct{}
+	// similarity enables fuzzy matching of names, DefaultSimilarityConfig by default.
+	similarity *SimilarityConfig
+
+	sources []NftCollectionSource
+	refreshLoop
+}
+
+type NftCollectionVerifierOption func(v *NftCollectionVerifier)
+
+// WithNftCollectionSources configures where the list of well-known collections comes from.
+// Sources are tried in order. By default, the list is downloaded from the ton-assets repository.
+func WithNftCollectionSources(sources ...NftCollectionSource) NftCollectionVerifierOption {
+	return func(v *NftCollectionVerifier) {
+		v.sources = sources
+	}
+}
+
+// WithNftCollectionRefreshInterval configures how often the list is refreshed, one hour by default.
+// A random delay up to jitter is added to every interval. A non-positive interval is ignored.
+func WithNftCollectionRefreshInterval(interval, jitter time.Duration) NftCollectionVerifierOption {
+	return func(v *NftCollectionVerifier) {
+		v.setInterval(interval, jitter)
+	}
+}
+
+// WithNftCollectionMaxAge configures the age of the list starting from which Status reports it as stale.
+// Zero means the list never gets stale once loaded.
+func WithNftCollectionMaxAge(maxAge time.Duration) NftCollectionVerifierOption {
+	return func(v *NftCollectionVerifier) {
+		v.maxAge = maxAge
+	}
+}
+
+// WithNftCollectionSimilarity configures fuzzy matching of names, nil disables it.
+func WithNftCollectionSimilarity(config *SimilarityConfig) NftCollectionVerifierOption {
+	return func(v *NftCollectionVerifier) {
+		v.similarity = config
+	}
+}
+
+// NewNftCollectionVerifier is NewNftCollectionVerifierContext with a background context.
+func NewNftCollectionVerifier(opts ...NftCollectionVerifierOption) *NftCollectionVerifier {
+	return NewNftCollectionVerifierContext(context.Background(), opts...)
+}
+
+// NewNftCollectionVerifierContext returns a verifier which refreshes the list of well-known collections
+// in background until the context is canceled or Close is called.
+func NewNftCollectionVerifierContext(ctx context.Context, opts ...NftCollectionVerifierOption) *NftCollectionVerifier {
+	config := DefaultSimilarityConfig()
+	verifier := &NftCollectionVerifier{
+		collections: map[string]map[tongo.AccountID]Collection{},
+		similarity:  &config,
+		sources:     []NftCollectionSource{NewHTTPNftCollectionSource(collectionsPath)},
+		refreshLoop: newRefreshLoop(),
+	}
+	for _, o := range opts {
+		o(verifier)
+	}
+	verifier.start(ctx, verifier.Refresh)
+	return verifier
+}
+
+// Refresh downloads the list of well-known collections right away.
+func (verifier *NftCollectionVerifier) Refresh(ctx context.Context) error {
+	verifier.refreshMu.Lock()
+	defer verifier.refreshMu.Unlock()
+	fetch := func(ctx context.Context) ([]Collection, error) {
+		return fetchCollections(ctx, verifier.sources)
+	}
+	return refreshList(ctx, &verifier.refreshLoop, fetch, func(collections []Collection) {
+		verifier.setCollections(collections, time.Now())
+	})
+}
+
+func (verifier *NftCollectionVerifier) setCollections(known []Collection, timestamp time.Time) {
+	collections := make(map[string]map[tongo.AccountID]Collection, len(known))
+	addresses := make(map[tongo.AccountID]struct{}, len(known))
+	for _, item := range known {
+		addresses[item.Address] = struct{}{}
+		name := NormalizeString(item.Name)
+		if name == "" {
+			continue
+		}
+		if _, ok := collections[name]; !ok {
+			collections[name] = map[tongo.AccountID]Collection{}
+		}
+		collections[name][item.Address] = item
+	}
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	verifier.collections = collections
+	verifier.addresses = addresses
+	verifier.setRefreshed(timestamp, len(known))
+}
+
+// Status returns the state of the list of well-known collections.
+func (verifier *NftCollectionVerifier) Status() NftCollectionVerifierStatus {
+	status := verifier.status()
+	return NftCollectionVerifierStatus{
+		LastRefresh: status.LastRefresh,
+		LastAttempt: status.LastAttempt,
+		LastError:   status.LastError,
+		Collections: status.Count,
+		Stale:       status.Stale,
+	}
+}
+
+// Check returns a verdict for the collection if its name imitates any of the well-known collections.
+func (verifier *NftCollectionVerifier) Check(address tongo.AccountID, name string) NftCollectionVerdict {
+	normalized := NormalizeString(name)
+	if normalized == "" {
+		return NftCollectionVerdict{}
+	}
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+	if _, ok := verifier.addresses[address]; ok {
+		// this collection is in our list of well-known collections
+		return NftCollectionVerdict{}
+	}
+	if collections, ok := verifier.collections[normalized]; ok {
+		impersonated := anyCollection(collections)
+		return NftCollectionVerdict{Reason: ImpersonatesCollection, Name: normalized, Impersonated: &impersonated}
+	}
+	if verifier.similarity == nil {
+		return NftCollectionVerdict{}
+	}
+	verdict, bestKnown, found := NftCollectionVerdict{}, "", false
+	for known, collections := range verifier.collections {
+		_, distance, ok := compareSymbols(verifier.similarity, normalized, known)
+		if !ok {
+			continue
+		}
+		// ties are broken by the normalized name and then by the address like in closestJetton
+		if found && (distance > verdict.Distance || distance == verdict.Distance && known > bestKnown) {
+			continue
+		}
+		impersonated := anyCollection(collections)
+		verdict, bestKnown, found = NftCollectionVerdict{Reason: SimilarToCollection, Name: normalized, Impersonated: &impersonated, Distance: distance}, known, true
+	}
+	return verdict
+}
+
+// anyCollection returns the collection with the smallest address, so the result doesn't depend on map ordering.
+func anyCollection(collections map[tongo.AccountID]Collection) Collection {
+	var result Collection
+	first := true
+	for address, collection := range collections {
+		if first || address.ToRaw() < result.Address.ToRaw() {
+			result, first = collection, false
+		}
+	}
+	return result
+}
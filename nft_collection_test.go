@@ -0,0 +1,115 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testKnownCollections = []Collection{
+	{Name: "TON Diamonds", Address: testAddress(0xd1)},
+	{Name: "Anonymous Telegram Numbers", Address: testAddress(0xd2)},
+	{Name: "Telegram Usernames", Address: testAddress(0xd3)},
+}
+
+func TestNftCollectionVerifier_Check(t *testing.T) {
+	verifier := NewNftCollectionVerifier(WithNftCollectionSources(StaticNftCollectionSource(testKnownCollections)))
+	defer verifier.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, verifier.WaitReady(ctx))
+	require.Equal(t, 3, verifier.Status().Collections)
+
+	tests := []struct {
+		name       string
+		collection string
+		address    byte
+		wantReason TypeOfCollectionReason
+		wantName   string
+	}{
+		{name: "original", collection: "TON Diamonds", address: 0xd1},
+		{name: "same name", collection: "TON  DIAMONDS", address: 0xe1, wantReason: ImpersonatesCollection, wantName: "TON Diamonds"},
+		{name: "cyrillic", collection: "ТОN Diamonds", address: 0xe1, wantReason: ImpersonatesCollection, wantName: "TON Diamonds"},
+		{name: "padding", collection: "Telegram Usernames 2", address: 0xe1, wantReason: SimilarToCollection, wantName: "Telegram Usernames"},
+		{name: "typo", collection: "Anonymous Telegram Numbrs", address: 0xe1, wantReason: SimilarToCollection, wantName: "Anonymous Telegram Numbers"},
+		{name: "unrelated", collection: "Punks", address: 0xe1},
+		{name: "empty", collection: "", address: 0xe1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := verifier.Check(testAddress(tt.address), tt.collection)
+			require.Equal(t, tt.wantReason, verdict.Reason)
+			require.Equal(t, tt.wantReason != "", verdict.Flagged())
+			if tt.wantName != "" {
+				require.Equal(t, tt.wantName, verdict.Impersonated.Name)
+			}
+		})
+	}
+
+	exact := NewNftCollectionVerifier(
+		WithNftCollectionSources(StaticNftCollectionSource(testKnownCollections)),
+		WithNftCollectionSimilarity(nil))
+	defer exact.Close()
+	require.Nil(t, exact.Refresh(ctx))
+	require.False(t, exact.Check(testAddress(0xe1), "Telegram Usernames 2").Flagged())
+}
+
+func TestNftCollectionVerifier_Check_ties(t *testing.T) {
+	config := DefaultSimilarityConfig()
+	verifier := &NftCollectionVerifier{similarity: &config}
+	for i := 0; i < 20; i++ {
+		verifier.setCollections([]Collection{
+			{Name: "Lost Dogs B", Address: testAddress(0xd3)},
+			{Name: "Lost Dogs A", Address: testAddress(0xd2)},
+			{Name: "Lost Dogs A", Address: testAddress(0xd1)},
+		}, time.Now())
+		verdict := verifier.Check(testAddress(0xe1), "Lost Dogs C")
+		require.Equal(t, SimilarToCollection, verdict.Reason)
+		require.Equal(t, testAddress(0xd1), verdict.Impersonated.Address)
+	}
+}
+
+func TestNftCollectionVerifier_Status(t *testing.T) {
+	verifier := &NftCollectionVerifier{}
+	WithNftCollectionMaxAge(time.Hour)(verifier)
+	require.True(t, verifier.Status().Stale)
+
+	verifier.sources = []NftCollectionSource{StaticNftCollectionSource(testKnownCollections)}
+	require.Nil(t, verifier.Refresh(context.Background()))
+	status := verifier.Status()
+	require.False(t, status.Stale)
+	require.Nil(t, status.LastError)
+	require.Equal(t, len(testKnownCollections), status.Collections)
+
+	verifier.lastRefresh = time.Now().Add(-2 * time.Hour)
+	require.True(t, verifier.Status().Stale)
+}
+
+func TestHTTPNftCollectionSource(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[{"name": "TON Diamonds", "address": "0:00000000000000000000000000000000000000000000000000000000000000d1"}]`))
+	}))
+	defer server.Close()
+
+	verifier := NewNftCollectionVerifier(
+		WithNftCollectionSources(NewHTTPNftCollectionSource(server.URL)),
+		WithNftCollectionRefreshInterval(time.Hour, 0))
+	defer verifier.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, verifier.WaitReady(ctx))
+	require.Nil(t, verifier.Refresh(ctx))
+	require.Equal(t, 1, verifier.Status().Collections)
+	require.Equal(t, ImpersonatesCollection, verifier.Check(testAddress(0xe1), "Ton Diamonds").Reason)
+}
@@ -0,0 +1,99 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestNftMetadata_JSON(t *testing.T) {
+	var metadata NftMetadata
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"name": "Voucher #1",
+		"description": "Claim your reward",
+		"image": "https://example.com/1.png",
+		"external_url": "https://claim-reward.xyz",
+		"attributes": [
+			{"trait_type": "Reward", "value": "1000 TON"},
+			{"trait_type": "Level", "value": 5},
+			{"value": null}
+		]
+	}`), &metadata))
+	require.Equal(t, "https://claim-reward.xyz", metadata.ExternalURL)
+	require.Equal(t, []NftAttribute{
+		{TraitType: "Reward", Value: "1000 TON"},
+		{TraitType: "Level", Value: "5"},
+		{},
+	}, metadata.Attributes)
+}
+
+func TestCheckNft(t *testing.T) {
+	rules := LoadRules([]byte(`
+rules:
+  - pattern: "claim.*reward"
+    action: mark_scam
+    type: nft
+    field: description
+  - pattern: "reward"
+    action: drop
+    type: nft
+    field: attributes
+  - pattern: "voucher"
+    action: drop
+    type: nft
+  - pattern: "^0:0+f1$"
+    action: accept
+    type: nft
+    field: collection
+  - pattern: "whatever"
+    action: drop
+    type: nft
+    field: unknown
+`), true)
+	require.Len(t, rules, 4, "rules with unknown fields are skipped")
+	collection := testAddress(0xf1)
+
+	tests := []struct {
+		name     string
+		metadata NftMetadata
+		want     NftVerdict
+	}{
+		{
+			name:     "description",
+			metadata: NftMetadata{Name: "Gift", Description: "Claim your REWARD at scam.xyz"},
+			want:     NftVerdict{Action: MarkScam, Field: NftDescription, Value: "Claim your REWARD at scam.xyz"},
+		},
+		{
+			name:     "field rule doesn't apply to other fields",
+			metadata: NftMetadata{Name: "Claim reward"},
+			want:     NftVerdict{Action: UnKnown},
+		},
+		{
+			name:     "attribute",
+			metadata: NftMetadata{Name: "Gift", Attributes: []NftAttribute{{TraitType: "Level", Value: "1"}, {TraitType: "Reward", Value: "1000 TON"}}},
+			want:     NftVerdict{Action: Drop, Field: NftAttributes, Value: "Reward: 1000 TON"},
+		},
+		{
+			name:     "any field",
+			metadata: NftMetadata{Name: "Gift", ExternalURL: "https://voucher.xyz"},
+			want:     NftVerdict{Action: Drop, Field: NftExternalURL, Value: "https://voucher.xyz"},
+		},
+		{
+			name:     "collection",
+			metadata: NftMetadata{Name: "Gift", Collection: &collection},
+			want:     NftVerdict{Action: Accept, Field: NftCollection, Value: collection.ToRaw()},
+		},
+		{
+			name:     "clean",
+			metadata: NftMetadata{Name: "Punk #1", Description: "A punk", Collection: &tongo.AccountID{}},
+			want:     NftVerdict{Action: UnKnown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, CheckNft(rules, tt.metadata))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package scam_backoffice_rules
+
+import (
+	"fmt"
+
+	"github.com/mozillazg/go-unidecode"
+	"golang.org/x/text/transform"
+)
+
+// normalizationStep is a named stage of NormalizeString or NormalizeComment.
+type normalizationStep struct {
+	name        string
+	transformer transform.Transformer
+}
+
+func chainSteps(steps []normalizationStep) transform.Transformer {
+	transformers := make([]transform.Transformer, 0, len(steps))
+	for _, step := range steps {
+		transformers = append(transformers, step.transformer)
+	}
+	return transform.Chain(transformers...)
+}
+
+// NormalizationTrace describes how a string was changed by every normalization step.
+// It is intended for debugging why a text matched or didn't match a rule
+// or why a jetton symbol collided with a well-known one.
+type NormalizationTrace struct {
+	Input  string                    `json:"input"`
+	Output string                    `json:"output"`
+	Steps  []NormalizationStepResult `json:"steps"`
+	// Error is set when NormalizeComment rejects the text.
+	Error string `json:"error,omitempty"`
+}
+
+// NormalizationStepResult is the intermediate string produced by a single step
+// together with the runes the step changed.
+type NormalizationStepResult struct {
+	Step    string           `json:"step"`
+	Output  string           `json:"output"`
+	Changes []RuneProvenance `json:"changes,omitempty"`
+}
+
+// RuneProvenance explains what happened to a single rune during a step.
+// To is empty when the rune was removed.
+type RuneProvenance struct {
+	Position int    `json:"position"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Source   string `json:"source,omitempty"`
+}
+
+// TraceNormalizeString runs the same pipeline as NormalizeString and records every intermediate result.
+func TraceNormalizeString(s string) NormalizationTrace {
+	trace := NormalizationTrace{Input: s}
+	s = traceSteps(&trace, stringNormalizationSteps, s)
+	s = traceStep(&trace, "unidecode", s, unidecode.Unidecode)
+	trace.Output = s
+	return trace
+}
+
+// TraceNormalizeComment runs the same pipeline as NormalizeComment and records every intermediate result.
+func TraceNormalizeComment(comment string) NormalizationTrace {
+	trace := NormalizationTrace{Input: comment}
+	comment = traceSteps(&trace, commentNormalizationSteps, comment)
+	if r, ok := firstInvalidSymbol(comment); ok {
+		trace.Error = fmt.Sprintf("invalid character %q (%U)", r, r)
+		return trace
+	}
+	trace.Output = comment
+	return trace
+}
+
+func traceSteps(trace *NormalizationTrace, steps []normalizationStep, s string) string {
+	for _, step := range steps {
+		transformer := step.transformer
+		s = traceStep(trace, step.name, s, func(in string) string {
+			out, _, _ := transform.String(transformer, in)
+			return out
+		})
+	}
+	return s
+}
+
+// traceStep applies fn to the whole string and then to every rune separately
+// to find out which runes were changed by the step.
+// Steps working on rune sequences (like NFC composition) are reported only by their output.
+func traceStep(trace *NormalizationTrace, name string, s string, fn func(string) string) string {
+	result := NormalizationStepResult{Step: name, Output: fn(s)}
+	position := 0
+	for _, r := range s {
+		if to := fn(string(r)); to != string(r) {
+			result.Changes = append(result.Changes, RuneProvenance{
+				Position: position,
+				From:     string(r),
+				To:       to,
+				Source:   runeSource(name, r),
+			})
+		}
+		position++
+	}
+	trace.Steps = append(trace.Steps, result)
+	return result.Output
+}
+
+func runeSource(step string, r rune) string {
+	if step != "map_runes" {
+		return ""
+	}
+	if _, ok := mappingRunes[r]; ok {
+		return fmt.Sprintf("mappingRunes[%U]", r)
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceNormalizeString(t *testing.T) {
+	for _, input := range []string{"USD₮", "subbotin.ton", "Tést.ton", "  special*chars! ", "jU⁣SDT", "ТОN"} {
+		t.Run(input, func(t *testing.T) {
+			trace := TraceNormalizeString(input)
+			require.Equal(t, NormalizeString(input), trace.Output)
+			require.Equal(t, len(stringNormalizationSteps)+1, len(trace.Steps))
+			require.Equal(t, trace.Output, trace.Steps[len(trace.Steps)-1].Output)
+		})
+	}
+
+	trace := TraceNormalizeString("USD₮")
+	var mapped []RuneProvenance
+	for _, step := range trace.Steps {
+		if step.Step == "map_runes" {
+			mapped = step.Changes
+		}
+	}
+	require.Equal(t, []RuneProvenance{{Position: 3, From: "₮", To: "T", Source: "mappingRunes[U+20AE]"}}, mapped)
+}
+
+func TestTraceNormalizeComment(t *testing.T) {
+	for _, input := range []string{"Hello World", "Claim at tonkeeper.com", "bad ☭ symbol"} {
+		t.Run(input, func(t *testing.T) {
+			trace := TraceNormalizeComment(input)
+			expected, err := NormalizeComment(input)
+			require.Equal(t, err != nil, trace.Error != "")
+			require.Equal(t, expected, trace.Output)
+		})
+	}
+}
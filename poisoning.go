@@ -0,0 +1,146 @@
+package scam_backoffice_rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tonkeeper/tongo"
+)
+
+// TypeOfAddressForm is a textual representation of an address compared by AddressPoisoningDetector.
+type TypeOfAddressForm string
+
+const (
+	BounceableForm    TypeOfAddressForm = "bounceable"
+	NonBounceableForm TypeOfAddressForm = "non_bounceable"
+	RawForm           TypeOfAddressForm = "raw"
+)
+
+const (
+	defaultPoisoningPrefixLength = 4
+	defaultPoisoningSuffixLength = 4
+	defaultPoisoningMinScore     = 0.75
+	// userFriendlyTagLength is the number of leading characters of a user-friendly address
+	// encoding flags and a workchain, they are the same for almost all addresses, like "UQ" or "EQ".
+	userFriendlyTagLength = 2
+)
+
+// PoisoningMatch describes a sender imitating a known counterparty.
+type PoisoningMatch struct {
+	// Imitated is the known counterparty whose address is imitated by the sender.
+	Imitated tongo.AccountID `json:"imitated"`
+	// Form is the representation of the addresses sharing the prefix and the suffix.
+	Form TypeOfAddressForm `json:"form"`
+	// Prefix and Suffix are the numbers of matching leading and trailing characters.
+	Prefix int `json:"prefix"`
+	Suffix int `json:"suffix"`
+	// Score is the share of characters matching within the configured prefix and suffix lengths, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+func (m PoisoningMatch) String() string {
+	return fmt.Sprintf("imitates %v (%v, prefix %v, suffix %v, score %.2f)", m.Imitated.ToRaw(), m.Form, m.Prefix, m.Suffix, m.Score)
+}
+
+// AddressPoisoningDetector finds senders with vanity addresses resembling addresses a user transacted with.
+// Wallets usually show only a few leading and trailing characters of an address,
+// so a user copying an address from the history can pick the attacker's one.
+type AddressPoisoningDetector struct {
+	prefixLength int
+	suffixLength int
+	minScore     float64
+}
+
+type AddressPoisoningOption func(d *AddressPoisoningDetector)
+
+// WithMatchLengths configures the numbers of leading and trailing characters compared, 4 and 4 by default.
+// Leading flags of user-friendly addresses like "UQ" are not counted.
+func WithMatchLengths(prefix, suffix int) AddressPoisoningOption {
+	return func(d *AddressPoisoningDetector) {
+		d.prefixLength = prefix
+		d.suffixLength = suffix
+	}
+}
+
+// WithMinPoisoningScore configures the minimum score of a reported match, 0.75 by default.
+func WithMinPoisoningScore(score float64) AddressPoisoningOption {
+	return func(d *AddressPoisoningDetector) {
+		d.minScore = score
+	}
+}
+
+func NewAddressPoisoningDetector(opts ...AddressPoisoningOption) *AddressPoisoningDetector {
+	d := &AddressPoisoningDetector{
+		prefixLength: defaultPoisoningPrefixLength,
+		suffixLength: defaultPoisoningSuffixLength,
+		minScore:     defaultPoisoningMinScore,
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
+// Check returns the known counterparty imitated by the sender.
+// Nothing is returned if the sender is one of the counterparties.
+func (d *AddressPoisoningDetector) Check(sender tongo.AccountID, counterparties []tongo.AccountID) (PoisoningMatch, bool) {
+	for _, counterparty := range counterparties {
+		if counterparty == sender {
+			return PoisoningMatch{}, false
+		}
+	}
+	senderForms := addressForms(sender)
+	best, found := PoisoningMatch{}, false
+	for _, counterparty := range counterparties {
+		for i, form := range addressForms(counterparty) {
+			match := d.compare(senderForms[i].value, form.value)
+			match.Imitated, match.Form = counterparty, form.form
+			if match.Score >= d.minScore && (!found || match.Score > best.Score) {
+				best, found = match, true
+			}
+		}
+	}
+	return best, found
+}
+
+func (d *AddressPoisoningDetector) compare(sender, known string) PoisoningMatch {
+	prefix := commonPrefixLength(sender, known)
+	suffix := commonPrefixLength(reverseString(sender), reverseString(known))
+	window := d.prefixLength + d.suffixLength
+	if window <= 0 {
+		return PoisoningMatch{Prefix: prefix, Suffix: suffix}
+	}
+	matched := minInt(prefix, d.prefixLength) + minInt(suffix, d.suffixLength)
+	return PoisoningMatch{Prefix: prefix, Suffix: suffix, Score: float64(matched) / float64(window)}
+}
+
+type addressForm struct {
+	form  TypeOfAddressForm
+	value string
+}
+
+// addressForms returns representations of an address shown to users without their common tags.
+func addressForms(address tongo.AccountID) []addressForm {
+	raw := address.ToRaw()
+	return []addressForm{
+		{form: NonBounceableForm, value: address.ToHuman(false, false)[userFriendlyTagLength:]},
+		{form: BounceableForm, value: address.ToHuman(true, false)[userFriendlyTagLength:]},
+		{form: RawForm, value: raw[strings.IndexByte(raw, ':')+1:]},
+	}
+}
+
+func commonPrefixLength(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func reverseString(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
@@ -0,0 +1,77 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+// vanityAddress returns an address sharing leading and trailing bytes with the given one.
+func vanityAddress(address tongo.AccountID) tongo.AccountID {
+	for i := 8; i < 24; i++ {
+		address.Address[i] ^= 0xff
+	}
+	return address
+}
+
+func TestAddressPoisoningDetector_Check(t *testing.T) {
+	known := tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	other := tongo.MustParseAccountID("0:0000000000000000000000000000000000000000000000000000000000000001")
+	sender := vanityAddress(known)
+	detector := NewAddressPoisoningDetector()
+
+	match, ok := detector.Check(sender, []tongo.AccountID{other, known})
+	require.True(t, ok)
+	require.Equal(t, known, match.Imitated)
+	require.Equal(t, RawForm, match.Form)
+	require.Equal(t, 16, match.Prefix)
+	require.Equal(t, 16, match.Suffix)
+	require.Equal(t, 1.0, match.Score)
+
+	_, ok = detector.Check(sender, []tongo.AccountID{other})
+	require.False(t, ok)
+	_, ok = detector.Check(known, []tongo.AccountID{known, other})
+	require.False(t, ok, "a known counterparty is not an attacker")
+
+	strict := NewAddressPoisoningDetector(WithMatchLengths(20, 20), WithMinPoisoningScore(0.9))
+	_, ok = strict.Check(sender, []tongo.AccountID{known})
+	require.False(t, ok)
+	match, ok = NewAddressPoisoningDetector(WithMatchLengths(20, 20), WithMinPoisoningScore(0.8)).Check(sender, []tongo.AccountID{known})
+	require.True(t, ok)
+	require.Equal(t, 0.8, match.Score)
+}
+
+func TestAddressPoisoningDetector_compare(t *testing.T) {
+	detector := NewAddressPoisoningDetector()
+	tests := []struct {
+		sender, known string
+		wantScore     float64
+	}{
+		{sender: "AbCdXXXXwXyZ", known: "AbCdYYYYwXyZ", wantScore: 1},
+		{sender: "AbCXXXXXXXXZ", known: "AbCdYYYYwXyZ", wantScore: 0.5},
+		{sender: "AbCdeXXXXwXyZ", known: "AbCdfYYYYwXyZ", wantScore: 1},
+		{sender: "XXXXXXXXXXXX", known: "AbCdYYYYwXyZ", wantScore: 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.wantScore, detector.compare(tt.sender, tt.known).Score, tt.sender)
+	}
+}
+
+func TestCheckTransfer(t *testing.T) {
+	known := tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: address_poisoning
+    action: mark_scam
+    type: comment
+  - pattern: "^gift$"
+    action: drop
+    type: comment
+`), true)
+
+	require.Equal(t, MarkScam, CheckTransfer(rules, Transfer{Sender: vanityAddress(known), Counterparties: []tongo.AccountID{known}}))
+	require.Equal(t, Drop, CheckTransfer(rules, Transfer{Sender: known, Comment: "Gift", Counterparties: []tongo.AccountID{known}}))
+	require.Equal(t, UnKnown, CheckTransfer(rules, Transfer{Sender: known, Comment: "hello"}))
+	require.Equal(t, UnKnown, CheckActionOfType(rules, "hello", Comment), "comments without transfers are not poisoning")
+}
@@ -0,0 +1,122 @@
+package scam_backoffice_rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// punycode parameters, see RFC 3492.
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodeACEPrefix   = "xn--"
+)
+
+// decodeDomain converts punycode labels ("xn--...") of a domain into unicode.
+// Labels which fail to decode are kept as is.
+func decodeDomain(domain string) string {
+	if !strings.Contains(domain, punycodeACEPrefix) {
+		return domain
+	}
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, punycodeACEPrefix) {
+			continue
+		}
+		decoded, err := decodePunycode(label[len(punycodeACEPrefix):])
+		if err != nil {
+			continue
+		}
+		labels[i] = decoded
+	}
+	return strings.Join(labels, ".")
+}
+
+// decodePunycode decodes a single punycode label without the ACE prefix.
+func decodePunycode(encoded string) (string, error) {
+	var output []rune
+	basic := strings.LastIndexByte(encoded, '-')
+	if basic > 0 {
+		for _, r := range encoded[:basic] {
+			if r >= 0x80 {
+				return "", fmt.Errorf("non-basic code point %U", r)
+			}
+			output = append(output, r)
+		}
+		encoded = encoded[basic+1:]
+	} else if basic == 0 {
+		encoded = encoded[1:]
+	}
+
+	n, bias, i := punycodeInitialN, punycodeInitialBias, 0
+	for pos := 0; pos < len(encoded); {
+		oldI, w := i, 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if pos >= len(encoded) {
+				return "", fmt.Errorf("truncated punycode")
+			}
+			digit, ok := punycodeDigit(encoded[pos])
+			if !ok {
+				return "", fmt.Errorf("invalid punycode digit %q", encoded[pos])
+			}
+			pos++
+			i += digit * w
+			t := k - bias
+			if t < punycodeTMin {
+				t = punycodeTMin
+			} else if t > punycodeTMax {
+				t = punycodeTMax
+			}
+			if digit < t {
+				break
+			}
+			w *= punycodeBase - t
+			if w > 1<<24 || i > 1<<24 {
+				return "", fmt.Errorf("punycode overflow")
+			}
+		}
+		bias = punycodeAdapt(i-oldI, len(output)+1, oldI == 0)
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > 0x10FFFF {
+			return "", fmt.Errorf("punycode overflow")
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+	return string(output), nil
+}
+
+func punycodeDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	}
+	return 0, false
+}
+
+func punycodeAdapt(delta, numPoints int, firstTime bool) int {
+	if firstTime {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
@@ -0,0 +1,220 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/labstack/gommon/log"
+)
+
+// refreshLoop keeps a list of well-known items up-to-date in background.
+// It is embedded by verifiers, so they share the same lifecycle: Ready, WaitReady and Close.
+type refreshLoop struct {
+	refreshInterval time.Duration
+	// refreshJitter is a maximum random delay added to refreshInterval,
+	// so many instances don't hit the source at the same moment.
+	refreshJitter time.Duration
+	// ready is closed after the first successful refresh.
+	ready     chan struct{}
+	readyOnce sync.Once
+	cancel    context.CancelFunc
+	// done is closed when the background refresh loop exits.
+	done chan struct{}
+	// refreshMu serializes refreshes.
+	refreshMu sync.Mutex
+
+	// statusMu protects the refresh status below.
+	statusMu    sync.RWMutex
+	lastRefresh time.Time
+	lastAttempt time.Time
+	lastError   error
+	count       int
+	// maxAge is the age of the list starting from which it is considered stale, zero means never.
+	maxAge time.Duration
+}
+
+// refreshStatus is a snapshot of the refresh status, verifiers expose it with their own Status types.
+type refreshStatus struct {
+	LastRefresh time.Time
+	LastAttempt time.Time
+	LastError   error
+	Count       int
+	Stale       bool
+}
+
+// setInterval configures refreshInterval and refreshJitter.
+// A non-positive interval would hammer the source in a hot loop, so it is ignored and the current one is kept.
+func (l *refreshLoop) setInterval(interval, jitter time.Duration) {
+	if interval <= 0 {
+		log.Warnf("ignoring non-positive refresh interval %v, keeping %v", interval, l.refreshInterval)
+	} else {
+		l.refreshInterval = interval
+	}
+	if jitter < 0 {
+		jitter = 0
+	}
+	l.refreshJitter = jitter
+}
+
+func newRefreshLoop() refreshLoop {
+	return refreshLoop{
+		refreshInterval: time.Hour,
+		ready:           make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// start runs refresh in background until the context is canceled or Close is called.
+func (l *refreshLoop) start(ctx context.Context, refresh func(ctx context.Context) error) {
+	ctx, l.cancel = context.WithCancel(ctx)
+	go l.run(ctx, refresh)
+}
+
+func (l *refreshLoop) run(ctx context.Context, refresh func(ctx context.Context) error) {
+	defer close(l.done)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		_ = retry.Do(func() error {
+			return refresh(ctx)
+		}, retry.Attempts(3), retry.Delay(5*time.Second), retry.Context(ctx))
+
+		delay := l.refreshInterval
+		if l.refreshJitter > 0 {
+			delay += time.Duration(random.Int63n(int64(l.refreshJitter)))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *refreshLoop) markReady() {
+	l.readyOnce.Do(func() {
+		if l.ready != nil {
+			close(l.ready)
+		}
+	})
+}
+
+// Ready returns a channel which is closed after the list of well-known items is loaded for the first time.
+func (l *refreshLoop) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// WaitReady blocks until the list of well-known items is loaded for the first time or the context is done.
+func (l *refreshLoop) WaitReady(ctx context.Context) error {
+	select {
+	case <-l.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background refresh and waits for it to exit.
+func (l *refreshLoop) Close() {
+	if l.cancel == nil {
+		return
+	}
+	l.cancel()
+	<-l.done
+}
+
+// status returns the state of the list of well-known items.
+func (l *refreshLoop) status() refreshStatus {
+	l.statusMu.RLock()
+	defer l.statusMu.RUnlock()
+	return refreshStatus{
+		LastRefresh: l.lastRefresh,
+		LastAttempt: l.lastAttempt,
+		LastError:   l.lastError,
+		Count:       l.count,
+		Stale:       l.staleLocked(),
+	}
+}
+
+// isStale returns true if the list has never been loaded or is older than maxAge.
+func (l *refreshLoop) isStale() bool {
+	l.statusMu.RLock()
+	defer l.statusMu.RUnlock()
+	return l.staleLocked()
+}
+
+func (l *refreshLoop) staleLocked() bool {
+	if l.lastRefresh.IsZero() {
+		return true
+	}
+	return l.maxAge > 0 && time.Since(l.lastRefresh) > l.maxAge
+}
+
+// setRefreshed records a list of count items downloaded at the given time.
+func (l *refreshLoop) setRefreshed(timestamp time.Time, count int) {
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+	l.lastRefresh = timestamp
+	l.count = count
+}
+
+// refreshList fetches items and passes them to install recording the refresh status.
+// The caller must hold refreshMu. ErrNotModified means the installed list is still fresh.
+func refreshList[T any](ctx context.Context, l *refreshLoop, fetch func(ctx context.Context) ([]T, error), install func(items []T)) error {
+	items, err := fetch(ctx)
+	now := time.Now()
+	l.statusMu.Lock()
+	l.lastAttempt = now
+	if errors.Is(err, ErrNotModified) {
+		// our list is up-to-date, no need to rebuild it
+		l.lastRefresh = now
+		l.lastError = nil
+		l.statusMu.Unlock()
+		l.markReady()
+		return nil
+	}
+	l.lastError = err
+	l.statusMu.Unlock()
+	if err != nil {
+		return err
+	}
+	install(items)
+	l.markReady()
+	return nil
+}
+
+// conditionalSource is a source sending conditional requests, like HTTPJettonSource.
+type conditionalSource interface {
+	// resetValidators makes the next request unconditional.
+	resetValidators()
+}
+
+// fetchFirst returns items of the first source which succeeds, what names the items in logs.
+// A not modified answer means the installed list is fresh only if it came from the same source,
+// so conditional sources forget their validators once a list of another source is returned.
+func fetchFirst[S any, T any](ctx context.Context, sources []S, fetch func(S, context.Context) ([]T, error), what string) ([]T, error) {
+	var err error
+	for i, source := range sources {
+		var items []T
+		items, err = fetch(source, ctx)
+		if err == nil || errors.Is(err, ErrNotModified) {
+			for j, other := range sources {
+				if conditional, ok := any(other).(conditionalSource); ok && j != i {
+					conditional.resetValidators()
+				}
+			}
+			return items, err
+		}
+		log.Errorf("failed to get %v from %T: %v", what, source, err)
+	}
+	if err == nil {
+		err = fmt.Errorf("no sources of %v configured", what)
+	}
+	return nil, err
+}
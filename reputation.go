@@ -0,0 +1,229 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/tonkeeper/tongo"
+)
+
+// TypeOfReputationLabel is a class of an account.
+type TypeOfReputationLabel string
+
+const (
+	TrustedReputation  TypeOfReputationLabel = "trusted"
+	ExchangeReputation TypeOfReputationLabel = "exchange"
+	ScamReputation     TypeOfReputationLabel = "scam"
+	UnknownReputation  TypeOfReputationLabel = "unknown"
+)
+
+// defaultReputationScores are scores of moderator decisions, from -1 for scam to 1 for trusted accounts.
+var defaultReputationScores = map[TypeOfReputationLabel]float64{
+	TrustedReputation:  1,
+	ExchangeReputation: 0.8,
+	ScamReputation:     -1,
+	UnknownReputation:  0,
+}
+
+func isReputationLabel(label TypeOfReputationLabel) bool {
+	_, ok := defaultReputationScores[label]
+	return ok
+}
+
+// Reputation is what we know about an account, like an exchange hot wallet or a reported scammer.
+type Reputation struct {
+	Address tongo.AccountID       `json:"address"`
+	Label   TypeOfReputationLabel `json:"label"`
+	// Score is from -1 for scam to 1 for trusted accounts.
+	Score float64 `json:"score"`
+	// Source is the provenance of the reputation, like "moderator:alice" or "exchanges.json".
+	Source    string    `json:"source,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ExpiresAt is the time the reputation stops being used, zero means never.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired returns true if the reputation can't be used at the given time.
+func (r Reputation) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// ErrNoReputation is returned by a store which knows nothing about an account or whose reputation has expired.
+var ErrNoReputation = errors.New("no reputation")
+
+// ReputationStore keeps reputations of accounts.
+// Stores backed by external services implement it to be used by rules and JettonVerifier.
+type ReputationStore interface {
+	Reputation(ctx context.Context, address tongo.AccountID) (Reputation, error)
+	SetReputation(ctx context.Context, reputation Reputation) error
+}
+
+// MemoryReputationStore keeps reputations in memory.
+type MemoryReputationStore struct {
+	// mu protects reputations
+	mu          sync.RWMutex
+	reputations map[tongo.AccountID]Reputation
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+func NewMemoryReputationStore(reputations ...Reputation) *MemoryReputationStore {
+	store := &MemoryReputationStore{reputations: make(map[tongo.AccountID]Reputation, len(reputations)), now: time.Now}
+	for _, reputation := range reputations {
+		store.reputations[reputation.Address] = reputation
+	}
+	return store
+}
+
+func (s *MemoryReputationStore) Reputation(ctx context.Context, address tongo.AccountID) (Reputation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reputation, ok := s.reputations[address]
+	if !ok || reputation.Expired(s.now()) {
+		return Reputation{}, ErrNoReputation
+	}
+	return reputation, nil
+}
+
+func (s *MemoryReputationStore) SetReputation(ctx context.Context, reputation Reputation) error {
+	if !isReputationLabel(reputation.Label) {
+		return fmt.Errorf("unknown reputation label %q", reputation.Label)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reputations[reputation.Address] = reputation
+	return nil
+}
+
+// Delete forgets the reputation of an account.
+func (s *MemoryReputationStore) Delete(address tongo.AccountID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reputations, address)
+}
+
+// Reputations returns all reputations including expired ones ordered by address.
+func (s *MemoryReputationStore) Reputations() []Reputation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reputations := make([]Reputation, 0, len(s.reputations))
+	for _, reputation := range s.reputations {
+		reputations = append(reputations, reputation)
+	}
+	sort.Slice(reputations, func(i, j int) bool {
+		return reputations[i].Address.ToRaw() < reputations[j].Address.ToRaw()
+	})
+	return reputations
+}
+
+// FileReputationStore is a MemoryReputationStore saved to a json file after every change.
+type FileReputationStore struct {
+	*MemoryReputationStore
+	path string
+	// saveMu serializes writes of the file.
+	saveMu sync.Mutex
+}
+
+// NewFileReputationStore loads reputations from a json file, a missing file means an empty store.
+func NewFileReputationStore(path string) (*FileReputationStore, error) {
+	store := &FileReputationStore{MemoryReputationStore: NewMemoryReputationStore(), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reputations []Reputation
+	if err := json.Unmarshal(data, &reputations); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	store.MemoryReputationStore = NewMemoryReputationStore(reputations...)
+	return store, nil
+}
+
+func (s *FileReputationStore) SetReputation(ctx context.Context, reputation Reputation) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if err := s.MemoryReputationStore.SetReputation(ctx, reputation); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Delete forgets the reputation of an account and saves the file.
+func (s *FileReputationStore) Delete(address tongo.AccountID) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.MemoryReputationStore.Delete(address)
+	return s.save()
+}
+
+// save replaces the file atomically like the jettons cache, it must be called with saveMu held.
+func (s *FileReputationStore) save() error {
+	data, err := json.MarshalIndent(s.Reputations(), "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path)
+}
+
+// ModeratorDecision is a verdict of a moderator about an account.
+type ModeratorDecision struct {
+	Address   tongo.AccountID
+	Label     TypeOfReputationLabel
+	Moderator string
+	Note      string
+	// TTL limits how long the decision is used, zero means forever.
+	TTL time.Duration
+}
+
+// RecordDecision saves a moderator decision to the store with the default score of its label.
+func RecordDecision(ctx context.Context, store ReputationStore, decision ModeratorDecision) error {
+	now := time.Now()
+	reputation := Reputation{
+		Address:   decision.Address,
+		Label:     decision.Label,
+		Score:     defaultReputationScores[decision.Label],
+		Source:    "moderator:" + decision.Moderator,
+		Note:      decision.Note,
+		UpdatedAt: now,
+	}
+	if decision.TTL > 0 {
+		reputation.ExpiresAt = now.Add(decision.TTL)
+	}
+	return store.SetReputation(ctx, reputation)
+}
+
+// lookupReputation returns the reputation of an account, errors of a store are logged and mean no reputation.
+func lookupReputation(ctx context.Context, store ReputationStore, address tongo.AccountID) (Reputation, bool) {
+	reputation, err := store.Reputation(ctx, address)
+	if err != nil {
+		if !errors.Is(err, ErrNoReputation) {
+			log.Errorf("failed to get reputation of %v: %v", address.ToRaw(), err)
+		}
+		return Reputation{}, false
+	}
+	return reputation, true
+}
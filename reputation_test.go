@@ -0,0 +1,158 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+)
+
+func TestMemoryReputationStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryReputationStore(
+		Reputation{Address: testAddress(1), Label: ExchangeReputation, Score: 0.8, Source: "exchanges.json"},
+		Reputation{Address: testAddress(2), Label: ScamReputation, Score: -1, ExpiresAt: now.Add(time.Hour)},
+	)
+	store.now = func() time.Time { return now }
+
+	reputation, err := store.Reputation(ctx, testAddress(1))
+	require.Nil(t, err)
+	require.Equal(t, ExchangeReputation, reputation.Label)
+	_, err = store.Reputation(ctx, testAddress(3))
+	require.ErrorIs(t, err, ErrNoReputation)
+
+	_, err = store.Reputation(ctx, testAddress(2))
+	require.Nil(t, err)
+	now = now.Add(time.Hour)
+	_, err = store.Reputation(ctx, testAddress(2))
+	require.ErrorIs(t, err, ErrNoReputation)
+	require.Len(t, store.Reputations(), 2)
+
+	require.NotNil(t, store.SetReputation(ctx, Reputation{Address: testAddress(3), Label: "vip"}))
+	store.Delete(testAddress(1))
+	_, err = store.Reputation(ctx, testAddress(1))
+	require.ErrorIs(t, err, ErrNoReputation)
+}
+
+func TestFileReputationStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "reputation.json")
+	store, err := NewFileReputationStore(path)
+	require.Nil(t, err)
+	require.Nil(t, RecordDecision(ctx, store, ModeratorDecision{Address: testAddress(1), Label: ScamReputation, Moderator: "alice", Note: "drainer"}))
+	require.Nil(t, RecordDecision(ctx, store, ModeratorDecision{Address: testAddress(2), Label: TrustedReputation, Moderator: "bob", TTL: time.Hour}))
+	require.Nil(t, store.Delete(testAddress(2)))
+
+	reloaded, err := NewFileReputationStore(path)
+	require.Nil(t, err)
+	reputation, err := reloaded.Reputation(ctx, testAddress(1))
+	require.Nil(t, err)
+	require.Equal(t, ScamReputation, reputation.Label)
+	require.Equal(t, -1.0, reputation.Score)
+	require.Equal(t, "moderator:alice", reputation.Source)
+	require.Equal(t, "drainer", reputation.Note)
+	require.True(t, reputation.ExpiresAt.IsZero())
+	_, err = reloaded.Reputation(ctx, testAddress(2))
+	require.ErrorIs(t, err, ErrNoReputation)
+}
+
+func TestSenderReputationRule(t *testing.T) {
+	store := NewMemoryReputationStore(
+		Reputation{Address: testAddress(1), Label: ExchangeReputation},
+		Reputation{Address: testAddress(2), Label: ScamReputation},
+	)
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: sender_reputation
+    labels: [trusted, exchange]
+    action: accept
+    type: comment
+  - matcher: sender_reputation
+    labels: [scam]
+    action: mark_scam
+    type: comment
+  - pattern: "airdrop"
+    action: drop
+    type: comment
+`), true, WithReputationStore(store))
+	require.Len(t, rules, 3)
+
+	require.Equal(t, Accept, CheckTransfer(rules, Transfer{Sender: testAddress(1), Comment: "airdrop"}))
+	require.Equal(t, MarkScam, CheckTransfer(rules, Transfer{Sender: testAddress(2), Comment: "hello"}))
+	require.Equal(t, Drop, CheckTransfer(rules, Transfer{Sender: testAddress(3), Comment: "airdrop"}))
+	require.Equal(t, Drop, CheckActionOfType(rules, "airdrop", Comment))
+
+	// rules without a store or with unknown labels are skipped
+	require.Len(t, LoadRules([]byte(`{"rules": [{"matcher": "sender_reputation", "labels": ["scam"], "action": "drop"}]}`), false), 0)
+	require.Len(t, LoadRules([]byte(`{"rules": [{"matcher": "sender_reputation", "labels": ["vip"], "action": "drop"}]}`), false, WithReputationStore(store)), 0)
+}
+
+func TestJettonVerifier_WithReputation(t *testing.T) {
+	trusted, scam := testAddress(1), testAddress(2)
+	store := NewMemoryReputationStore(
+		Reputation{Address: trusted, Label: TrustedReputation},
+		Reputation{Address: scam, Label: ScamReputation, Source: "moderator:alice"},
+	)
+	verifier := &JettonVerifier{}
+	WithReputation(store)(verifier)
+	verifier.updateJettons(testKnownJettons)
+
+	require.False(t, verifier.CheckJetton(trusted, "Tether USD", "USDT").Blacklisted())
+	verdict := verifier.CheckJetton(scam, "Kitty", "KIT")
+	require.Equal(t, ReportedScam, verdict.Reason)
+	require.Equal(t, SeverityCritical, verdict.Severity)
+	require.Equal(t, "moderator:alice", verdict.Reputation.Source)
+	require.Equal(t, ImpersonatesJetton, verifier.CheckJetton(testAddress(3), "Kitty", "AMBR").Reason)
+
+	require.Equal(t, []TypeOfJettonReason{ReportedScam}, verifier.CheckJettonMetadata(JettonMetadata{Address: scam, Name: "Kitty", Symbol: "KIT"}).Reasons())
+	require.False(t, verifier.CheckJettonMetadata(JettonMetadata{Address: trusted, Name: "Tether USD", Symbol: "USDT"}).Blacklisted())
+}
+
+// contextReputationStore fails lookups with a canceled context.
+type contextReputationStore struct {
+	*MemoryReputationStore
+}
+
+func (s contextReputationStore) Reputation(ctx context.Context, address tongo.AccountID) (Reputation, error) {
+	if err := ctx.Err(); err != nil {
+		return Reputation{}, err
+	}
+	return s.MemoryReputationStore.Reputation(ctx, address)
+}
+
+func TestJettonVerifier_CheckJettonContext(t *testing.T) {
+	scam := testAddress(2)
+	verifier := &JettonVerifier{}
+	WithReputation(contextReputationStore{NewMemoryReputationStore(Reputation{Address: scam, Label: ScamReputation})})(verifier)
+	verifier.updateJettons(testKnownJettons)
+
+	require.Equal(t, ReportedScam, verifier.CheckJettonContext(context.Background(), scam, "Kitty", "KIT").Reason)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.False(t, verifier.CheckJettonContext(ctx, scam, "Kitty", "KIT").Blacklisted())
+
+	metadata := JettonMetadata{Address: scam, Name: "Kitty", Symbol: "KIT"}
+	require.Equal(t, []TypeOfJettonReason{ReportedScam}, verifier.CheckJettonMetadataContext(context.Background(), metadata).Reasons())
+	require.False(t, verifier.CheckJettonMetadataContext(ctx, metadata).Blacklisted())
+}
+
+func TestCheckTransferContext(t *testing.T) {
+	scam := testAddress(2)
+	store := contextReputationStore{NewMemoryReputationStore(Reputation{Address: scam, Label: ScamReputation})}
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: sender_reputation
+    labels: [scam]
+    action: mark_scam
+    type: comment
+`), true, WithReputationStore(store))
+
+	require.Equal(t, MarkScam, CheckTransferContext(context.Background(), rules, Transfer{Sender: scam, Comment: "hello"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, UnKnown, CheckTransferContext(ctx, rules, Transfer{Sender: scam, Comment: "hello"}))
+}
@@ -1,6 +1,7 @@
 package scam_backoffice_rules
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
@@ -28,26 +29,167 @@ const (
 	Nft     TypeOfItem = "nft"
 )
 
+type TypeOfMatcher string
+
+const (
+	// Regexp matches a text against Pattern, it is used when a rule has no matcher.
+	Regexp TypeOfMatcher = "regexp"
+	// DomainIn matches a text mentioning a domain (or its subdomain) from List.
+	// Domains are compared by their skeletons (see DomainSkeleton), so "t0n-gift.com" matches "ton-gift.com".
+	DomainIn TypeOfMatcher = "domain_in"
+	// DomainNotIn matches a text mentioning a domain which is not in List.
+	DomainNotIn TypeOfMatcher = "domain_not_in"
+	// TldIn matches a text mentioning a domain with a top-level domain from List.
+	TldIn TypeOfMatcher = "tld_in"
+	// Homograph matches a text mentioning a domain which imitates a protected one, see HomographDetector.
+	Homograph TypeOfMatcher = "homograph"
+	// ImpersonatesHandle matches a text mentioning a telegram handle which imitates an official one, see HandleVerifier.
+	ImpersonatesHandle TypeOfMatcher = "impersonates_handle"
+	// SeedPhrase matches a text containing a mnemonic, a private key or asking for them, see DetectSecrets.
+	SeedPhrase TypeOfMatcher = "seed_phrase"
+	// AddressPoisoning matches a transfer from an address imitating a known counterparty, see CheckTransfer.
+	AddressPoisoning TypeOfMatcher = "address_poisoning"
+	// ImpersonatesDNS matches a text mentioning a .ton domain or a .t.me username which imitates a protected one
+	// and an NFT name which is such a name itself, see DNSNameVerifier.
+	ImpersonatesDNS TypeOfMatcher = "impersonates_dns"
+	// SenderReputation matches a transfer from an account with one of Labels in the reputation store,
+	// see WithReputationStore and CheckTransfer.
+	SenderReputation TypeOfMatcher = "sender_reputation"
+)
+
 type ConvertedRules struct {
-	Rules []struct {
-		Pattern string       `yaml:"pattern" json:"pattern"`
-		Action  TypeOfAction `yaml:"action" json:"action"`
-		Type    TypeOfItem   `yaml:"type" json:"type"`
-	} `yaml:"rules" json:"rules"`
+	Rules []ConvertedRule `yaml:"rules" json:"rules"`
+	// Lists are initial values of lists referenced by rules.
+	Lists map[string][]string `yaml:"lists" json:"lists"`
+}
+
+type ConvertedRule struct {
+	Pattern string        `yaml:"pattern" json:"pattern"`
+	Action  TypeOfAction  `yaml:"action" json:"action"`
+	Type    TypeOfItem    `yaml:"type" json:"type"`
+	Matcher TypeOfMatcher `yaml:"matcher" json:"matcher"`
+	// List is a name of a list in Lists used by a matcher.
+	List string `yaml:"list" json:"list"`
+	// Field limits a rule of the nft type to one field of NFT metadata, see CheckNft.
+	Field TypeOfNftField `yaml:"field" json:"field"`
+	// Labels are reputation labels of senders matched by the sender_reputation matcher.
+	Labels []TypeOfReputationLabel `yaml:"labels" json:"labels"`
 }
 
 type Rule struct {
 	Evaluate func(comment string) TypeOfAction
 	Type     TypeOfItem
+	// Field is a field of NFT metadata checked by the rule, all fields if empty.
+	Field TypeOfNftField
+	// evaluateInput is used by CheckAction instead of Evaluate when it is set.
+	evaluateInput func(input ruleInput) TypeOfAction
+}
+
+// ruleInput is a text checked by rules.
+type ruleInput struct {
+	raw        string
+	normalized string
+	// transfer is set when rules are checked with CheckTransfer.
+	transfer *Transfer
+	// ctx is set when rules are checked with CheckTransferContext, nil means context.Background().
+	ctx context.Context
+	// field is set when rules are checked with CheckNft.
+	field TypeOfNftField
+}
+
+func (rule Rule) evaluate(input ruleInput) TypeOfAction {
+	if rule.evaluateInput != nil {
+		return rule.evaluateInput(input)
+	}
+	return rule.Evaluate(input.normalized)
 }
 
 type Rules []Rule
 
-func LoadRules(bytesOfRules []byte, yamlConverted bool) Rules {
+type ruleOptions struct {
+	lists      *Lists
+	homograph  *HomographDetector
+	handles    *HandleVerifier
+	poisoning  *AddressPoisoningDetector
+	dns        *DNSNameVerifier
+	reputation ReputationStore
+}
+
+type RuleOption func(o *ruleOptions)
+
+// WithHomographDetector configures protected domains used by the homograph matcher.
+// By default, DefaultProtectedDomains are protected.
+func WithHomographDetector(detector *HomographDetector) RuleOption {
+	return func(o *ruleOptions) {
+		o.homograph = detector
+	}
+}
+
+// WithHandleVerifier configures official telegram handles used by the impersonates_handle matcher.
+// By default, DefaultProtectedHandles are protected.
+func WithHandleVerifier(verifier *HandleVerifier) RuleOption {
+	return func(o *ruleOptions) {
+		o.handles = verifier
+	}
+}
+
+// WithPoisoningDetector configures the detector used by the address_poisoning matcher.
+// By default, a detector with default match lengths is used.
+func WithPoisoningDetector(detector *AddressPoisoningDetector) RuleOption {
+	return func(o *ruleOptions) {
+		o.poisoning = detector
+	}
+}
+
+// WithDNSNameVerifier configures protected names used by the impersonates_dns matcher.
+// By default, DefaultProtectedDNSNames are protected.
+func WithDNSNameVerifier(verifier *DNSNameVerifier) RuleOption {
+	return func(o *ruleOptions) {
+		o.dns = verifier
+	}
+}
+
+// WithReputationStore configures the store used by the sender_reputation matcher.
+// Rules with this matcher are skipped if no store is configured.
+func WithReputationStore(store ReputationStore) RuleOption {
+	return func(o *ruleOptions) {
+		o.reputation = store
+	}
+}
+
+// WithLists configures lists referenced by rules.
+// Lists can be updated later and rules will pick up the changes.
+func WithLists(lists *Lists) RuleOption {
+	return func(o *ruleOptions) {
+		o.lists = lists
+	}
+}
+
+func LoadRules(bytesOfRules []byte, yamlConverted bool, opts ...RuleOption) Rules {
 	var rules Rules
 	var convertedRules ConvertedRules
 	var err error
 
+	options := ruleOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.lists == nil {
+		options.lists = NewLists()
+	}
+	if options.homograph == nil {
+		options.homograph = NewHomographDetector(DefaultProtectedDomains)
+	}
+	if options.handles == nil {
+		options.handles = NewHandleVerifier(DefaultProtectedHandles)
+	}
+	if options.poisoning == nil {
+		options.poisoning = NewAddressPoisoningDetector()
+	}
+	if options.dns == nil {
+		options.dns = NewDNSNameVerifier(DefaultProtectedDNSNames)
+	}
+
 	if yamlConverted {
 		err = yaml.Unmarshal(bytesOfRules, &convertedRules)
 	} else {
@@ -56,39 +198,183 @@ func LoadRules(bytesOfRules []byte, yamlConverted bool) Rules {
 	if err != nil {
 		log.Panicf("Failed to parse rules: %v", err)
 	}
+	for name, values := range convertedRules.Lists {
+		options.lists.Set(name, values)
+	}
 
 	for _, inputRule := range convertedRules.Rules {
-		compiledRegexp, err := regexp.Compile(inputRule.Pattern)
+		if inputRule.Matcher == "" {
+			inputRule.Matcher = Regexp
+		}
+		if inputRule.Field != "" && !isNftField(inputRule.Field) {
+			log.Warnf("Unknown field %v of %v rule", inputRule.Field, inputRule.Matcher)
+			continue
+		}
+		match, err := compileMatcher(inputRule, &options)
 		if err != nil {
-			fmt.Printf("Failed to compile regexp for pattern %s: %v", inputRule.Pattern, err)
+			log.Errorf("Failed to compile %v rule: %v", inputRule.Matcher, err)
 			continue
 		}
 
 		var rule Rule
 		action := inputRule.Action
-		rule.Evaluate = func(text string) TypeOfAction {
-			match := compiledRegexp.MatchString(text)
-			if !match {
+		evaluateInput := func(input ruleInput) TypeOfAction {
+			if !match(input) {
 				return UnKnown
 			}
 			return action
 		}
+		rule.evaluateInput = evaluateInput
+		rule.Evaluate = func(text string) TypeOfAction {
+			return evaluateInput(ruleInput{raw: text, normalized: text})
+		}
 		rule.Type = inputRule.Type
+		rule.Field = inputRule.Field
 		rules = append(rules, rule)
 	}
 
 	return rules
 }
 
+func compileMatcher(inputRule ConvertedRule, options *ruleOptions) (func(input ruleInput) bool, error) {
+	lists := options.lists
+	switch inputRule.Matcher {
+	case Regexp:
+		compiledRegexp, err := regexp.Compile(inputRule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", inputRule.Pattern, err)
+		}
+		return func(input ruleInput) bool {
+			return compiledRegexp.MatchString(input.normalized)
+		}, nil
+	case DomainIn:
+		return func(input ruleInput) bool {
+			// a blocklist is checked with lookalikes replaced, so "tonkeeреr-gift.com" and "t0nkeeper-gift.com" are still blocked
+			for _, text := range []string{input.raw, input.normalized} {
+				for _, domain := range ExtractDomains(text) {
+					if domainSkeletonInList(lists, inputRule.List, domain) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	case DomainNotIn:
+		return func(input ruleInput) bool {
+			// an allowlist is checked with the raw text, normalization would turn lookalikes into allowed domains
+			for _, domain := range ExtractDomains(input.raw) {
+				if !domainInList(lists, inputRule.List, domain) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case TldIn:
+		return func(input ruleInput) bool {
+			for _, url := range ExtractURLs(input.normalized) {
+				if lists.contains(inputRule.List, tldList, url.TLD) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Homograph:
+		detector := options.homograph
+		return func(input ruleInput) bool {
+			_, ok := detector.CheckText(input.raw)
+			return ok
+		}, nil
+	case ImpersonatesHandle:
+		verifier := options.handles
+		return func(input ruleInput) bool {
+			_, ok := verifier.CheckText(input.raw)
+			return ok
+		}, nil
+	case SeedPhrase:
+		return func(input ruleInput) bool {
+			// normalization breaks hex keys ("0" becomes "o"), so secrets are searched in the raw text
+			return len(DetectSecrets(input.raw)) > 0 || len(detectSolicitations(input.normalized)) > 0
+		}, nil
+	case AddressPoisoning:
+		detector := options.poisoning
+		return func(input ruleInput) bool {
+			if input.transfer == nil {
+				return false
+			}
+			_, ok := detector.Check(input.transfer.Sender, input.transfer.Counterparties)
+			return ok
+		}, nil
+	case ImpersonatesDNS:
+		verifier := options.dns
+		return func(input ruleInput) bool {
+			if input.field == NftName {
+				// items of TON DNS and Fragment collections are named after their domains
+				if _, ok := verifier.CheckName(input.raw); ok {
+					return true
+				}
+			}
+			_, ok := verifier.CheckText(input.raw)
+			return ok
+		}, nil
+	case SenderReputation:
+		store := options.reputation
+		if store == nil {
+			return nil, fmt.Errorf("no reputation store configured")
+		}
+		labels := make(map[TypeOfReputationLabel]struct{}, len(inputRule.Labels))
+		for _, label := range inputRule.Labels {
+			if !isReputationLabel(label) {
+				return nil, fmt.Errorf("unknown reputation label %q", label)
+			}
+			labels[label] = struct{}{}
+		}
+		return func(input ruleInput) bool {
+			if input.transfer == nil {
+				return false
+			}
+			ctx := input.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			reputation, ok := lookupReputation(ctx, store, input.transfer.Sender)
+			if !ok {
+				reputation.Label = UnknownReputation
+			}
+			_, ok = labels[reputation.Label]
+			return ok
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown matcher")
+}
+
+func domainInList(lists *Lists, name string, domain string) bool {
+	for _, parent := range parentDomains(domain) {
+		if lists.contains(name, domainList, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainSkeletonInList checks if a skeleton of the domain or of any of its parents is in the list.
+func domainSkeletonInList(lists *Lists, name string, domain string) bool {
+	for _, parent := range parentDomains(DomainSkeleton(domain)) {
+		if lists.contains(name, domainSkeletonList, parent) {
+			return true
+		}
+	}
+	return false
+}
+
 func CheckAction(rules Rules, comment string) TypeOfAction {
-	var err error
-	comment, err = NormalizeComment(comment)
+	normalized, err := NormalizeComment(comment)
 	if err != nil {
 		return Drop
 	}
+	input := ruleInput{raw: comment, normalized: normalized}
 	action := UnKnown
 	for _, rule := range rules {
-		action = rule.Evaluate(comment)
+		action = rule.evaluate(input)
 		if action != UnKnown {
 			break
 		}
@@ -97,15 +383,15 @@ func CheckAction(rules Rules, comment string) TypeOfAction {
 }
 
 func CheckActionOfType(rules Rules, text string, itemType TypeOfItem) TypeOfAction {
-	var err error
-	text, err = NormalizeComment(text)
+	normalized, err := NormalizeComment(text)
 	if err != nil {
 		return Drop
 	}
+	input := ruleInput{raw: text, normalized: normalized}
 	action := UnKnown
 	for _, rule := range rules {
 		if rule.Type == itemType || rule.Type == All {
-			action = rule.Evaluate(text)
+			action = rule.evaluate(input)
 			if action != UnKnown {
 				break
 			}
@@ -0,0 +1,156 @@
+package scam_backoffice_rules
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bip39English is the BIP39 english wordlist, TON mnemonics use the same words.
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+var mnemonicWords = func() map[string]struct{} {
+	words := map[string]struct{}{}
+	for _, word := range strings.Fields(bip39English) {
+		words[word] = struct{}{}
+	}
+	return words
+}()
+
+// minMnemonicWords is the length of the shortest BIP39 mnemonic, TON uses 24 words.
+const minMnemonicWords = 12
+
+type TypeOfSecret string
+
+const (
+	// Mnemonic is a sequence of mnemonic words, usually a fake seed phrase used as a bait.
+	Mnemonic TypeOfSecret = "mnemonic"
+	// HexPrivateKey is a string shaped like a hex encoded ed25519 private key.
+	HexPrivateKey TypeOfSecret = "hex_private_key"
+	// Base64PrivateKey is a string shaped like a base64 encoded ed25519 private key.
+	Base64PrivateKey TypeOfSecret = "base64_private_key"
+	// Solicitation is a phrase asking to share a seed phrase or a private key.
+	Solicitation TypeOfSecret = "solicitation"
+)
+
+// SecretFinding is a fragment of a text related to wallet secrets.
+type SecretFinding struct {
+	Type  TypeOfSecret `json:"type"`
+	Match string       `json:"match"`
+}
+
+var secretRegexp = struct {
+	hexKey    *regexp.Regexp
+	base64Key *regexp.Regexp
+	// hashContext is a word right before a string telling it is a hash and not a key, like "tx hash: <hex>".
+	hashContext *regexp.Regexp
+}{
+	// a raw address ("0:<hex>") and a hash in a link ("/transaction/<hex>", "#<hex>", "?hash=<hex>")
+	// have the same shape as a key
+	hexKey:      regexp.MustCompile(`(?:^|[^0-9a-fA-F:/#=])([0-9a-fA-F]{128}|[0-9a-fA-F]{64})(?:$|[^0-9a-fA-F])`),
+	base64Key:   regexp.MustCompile(`(?:^|[^A-Za-z0-9+/#=])([A-Za-z0-9+/]{86}==|[A-Za-z0-9+/]{43}=)`),
+	hashContext: regexp.MustCompile(`(?i)(?:^|[^\p{L}])(tx|txid|hash|transaction|транзакци[яи]|хеш|хэш)[^\p{L}\p{N}]{0,3}$`),
+}
+
+// maxHashContextLength is how far before a key shaped string hashContext is looked for.
+const maxHashContextLength = 32
+
+// solicitationPhrases are checked against both the lowercased raw text and the normalized one,
+// because NormalizeComment replaces some cyrillic letters with latin ones.
+var solicitationPhrases = []*regexp.Regexp{
+	// english
+	regexp.MustCompile(`(enter|send|share|type|input|provide|paste|submit|verify|confirm|restore|import|sync|validate)\b.{0,40}\b(seed|secret|recovery|mnemonic|backup|12|24)[ -]?(phrase|words?|key)`),
+	regexp.MustCompile(`(enter|send|share|type|input|provide|paste|submit|verify|confirm)\b.{0,40}\bprivate[ -]?key`),
+	// russian and ukrainian
+	regexp.MustCompile(`(введите|введи|отправьте|отправь|пришлите|укажите|вставьте|подтвердите|введіть|надішліть|вкажіть).{0,40}(секретн|сид|мнемон|фраз|24 слов|12 слов|приватн|ключ)`),
+	// spanish and portuguese
+	regexp.MustCompile(`(introduce|ingresa|envía|envia|escribe|digite|insira|informe).{0,40}(frase semilla|frase secreta|frase de recupera|24 palabras|12 palabras|24 palavras|12 palavras|clave privada|chave privada)`),
+	// turkish
+	regexp.MustCompile(`(kurtarma ifade|gizli ifade|24 kelime|12 kelime|özel anahtar).{0,40}(girin|gönderin|yazın|paylaşın)`),
+	// chinese
+	regexp.MustCompile(`(输入|发送|提供|填写|验证).{0,20}(助记词|私钥)`),
+}
+
+// DetectSecrets finds fake seed phrases, private key shaped strings
+// and phrases asking users to share their seed phrase.
+// It works both with raw and normalized (see NormalizeComment) texts.
+func DetectSecrets(text string) []SecretFinding {
+	var findings []SecretFinding
+	findings = append(findings, detectMnemonics(text)...)
+	findings = append(findings, detectKeys(text)...)
+	findings = append(findings, detectSolicitations(text)...)
+	return findings
+}
+
+func detectMnemonics(text string) []SecretFinding {
+	var findings []SecretFinding
+	// numbered lists like "1. word 2. word" are usual, so everything but letters separates words
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	flush := func(run []string) {
+		if len(run) < minMnemonicWords {
+			return
+		}
+		unique := map[string]struct{}{}
+		for _, word := range run {
+			unique[word] = struct{}{}
+		}
+		// a real mnemonic rarely repeats words, a usual english text does it all the time
+		if len(unique)*4 < len(run)*3 {
+			return
+		}
+		findings = append(findings, SecretFinding{Type: Mnemonic, Match: strings.Join(run, " ")})
+	}
+	start := 0
+	for i, word := range words {
+		if _, ok := mnemonicWords[word]; ok {
+			continue
+		}
+		flush(words[start:i])
+		start = i + 1
+	}
+	flush(words[start:])
+	return findings
+}
+
+func detectKeys(text string) []SecretFinding {
+	var findings []SecretFinding
+	for _, match := range secretRegexp.hexKey.FindAllStringSubmatchIndex(text, -1) {
+		if !isHashContext(text[:match[2]]) {
+			findings = append(findings, SecretFinding{Type: HexPrivateKey, Match: text[match[2]:match[3]]})
+		}
+	}
+	for _, match := range secretRegexp.base64Key.FindAllStringSubmatchIndex(text, -1) {
+		if !isHashContext(text[:match[2]]) {
+			findings = append(findings, SecretFinding{Type: Base64PrivateKey, Match: text[match[2]:match[3]]})
+		}
+	}
+	return findings
+}
+
+// isHashContext returns true if the text before a key shaped string says it is a transaction hash.
+func isHashContext(before string) bool {
+	if len(before) > maxHashContextLength {
+		before = before[len(before)-maxHashContextLength:]
+		// don't start in the middle of a multibyte rune
+		for len(before) > 0 && !utf8.RuneStart(before[0]) {
+			before = before[1:]
+		}
+	}
+	return secretRegexp.hashContext.MatchString(before)
+}
+
+func detectSolicitations(text string) []SecretFinding {
+	lowered := strings.ToLower(text)
+	for _, phrase := range solicitationPhrases {
+		if match := phrase.FindString(lowered); match != "" {
+			return []SecretFinding{{Type: Solicitation, Match: match}}
+		}
+	}
+	return nil
+}
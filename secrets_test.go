@@ -0,0 +1,66 @@
+package scam_backoffice_rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectSecrets(t *testing.T) {
+	fakeMnemonic := "abandon ability able about above absent absorb abstract absurd abuse access accident account accuse achieve acid acoustic acquire across act action actor actress actual"
+	tests := []struct {
+		name string
+		text string
+		want []TypeOfSecret
+	}{
+		{name: "usual comment", text: "thanks for the coffee, see you all tomorrow", want: nil},
+		{name: "uuid", text: "1f0e3dad-9990-4f1b-a5d5-6f1b6f1b6f1b", want: nil},
+		{name: "fake mnemonic", text: "my wallet: " + fakeMnemonic, want: []TypeOfSecret{Mnemonic}},
+		{name: "numbered mnemonic", text: numbered(fakeMnemonic), want: []TypeOfSecret{Mnemonic}},
+		{name: "hex key", text: "key " + strings.Repeat("a1", 32), want: []TypeOfSecret{HexPrivateKey}},
+		{name: "raw address", text: "0:" + strings.Repeat("a1", 32), want: nil},
+		{name: "tonviewer link", text: "paid, see https://tonviewer.com/transaction/" + strings.Repeat("a1", 32), want: nil},
+		{name: "hash in fragment", text: "explorer#" + strings.Repeat("a1", 32), want: nil},
+		{name: "tx hash", text: "refund for tx " + strings.Repeat("a1", 32), want: nil},
+		{name: "transaction hash", text: "Transaction hash: " + strings.Repeat("a1", 32), want: nil},
+		{name: "russian tx hash", text: "хеш транзакции: " + strings.Repeat("a1", 32), want: nil},
+		{name: "key after a word ending with tx", text: "ctx " + strings.Repeat("a1", 32), want: []TypeOfSecret{HexPrivateKey}},
+		{name: "base64 tx hash", text: "tx: " + strings.Repeat("Ab1+", 10) + "Ab1=", want: nil},
+		{name: "base64 key", text: "key " + strings.Repeat("Ab1+", 10) + "Ab1=", want: []TypeOfSecret{Base64PrivateKey}},
+		{name: "english", text: "Enter your 24 words to claim the reward", want: []TypeOfSecret{Solicitation}},
+		{name: "english private key", text: "please send us your private key", want: []TypeOfSecret{Solicitation}},
+		{name: "russian", text: "Введите секретную фразу для получения приза", want: []TypeOfSecret{Solicitation}},
+		{name: "spanish", text: "Ingresa tu frase semilla para reclamar", want: []TypeOfSecret{Solicitation}},
+		{name: "chinese", text: "请输入您的助记词领取奖励", want: []TypeOfSecret{Solicitation}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []TypeOfSecret
+			for _, finding := range DetectSecrets(tt.text) {
+				types = append(types, finding.Type)
+			}
+			require.Equal(t, tt.want, types)
+		})
+	}
+}
+
+func numbered(mnemonic string) string {
+	var b strings.Builder
+	for i, word := range strings.Fields(mnemonic) {
+		b.WriteString(string(rune('1'+i%9)) + ". " + word + "\n")
+	}
+	return b.String()
+}
+
+func TestSeedPhraseRule(t *testing.T) {
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: seed_phrase
+    action: mark_scam
+    type: all
+`), true)
+	require.Equal(t, MarkScam, CheckAction(rules, "Enter your 24 words at our site to claim"))
+	require.Equal(t, MarkScam, CheckActionOfType(rules, "key "+strings.Repeat("0f", 32), Nft))
+	require.Equal(t, UnKnown, CheckAction(rules, "Happy birthday!"))
+}
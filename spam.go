@@ -89,18 +89,29 @@ var mappingRunes = map[rune]rune{
 	0x06D4: '.', // . arabic full stop
 }
 
+// stringNormalizationSteps is the pipeline used by NormalizeString before the final unidecode pass.
+var stringNormalizationSteps = []normalizationStep{
+	{name: "nfkd", transformer: norm.NFKD}, //unicode decomposition and replacing similar characters
+	{name: "remove_marks", transformer: runes.Remove(runes.Predicate(unicode.IsMark))},
+	{name: "remove_spaces", transformer: runes.Remove(runes.Predicate(unicode.IsSpace))},
+	{name: "remove_zero_width", transformer: runes.Remove(runes.Predicate(isZeroWidthSpace))},
+	{name: "map_runes", transformer: runes.Map(runeMapper)},
+	{name: "lowercase", transformer: runes.Map(unicode.ToLower)},
+	{name: "remove_punct", transformer: runes.Remove(runes.Predicate(unicode.IsPunct))},
+	{name: "nfc", transformer: norm.NFC}, //return back for usual unicode form
+}
+
+// commentNormalizationSteps is the pipeline used by NormalizeComment before symbols are validated.
+var commentNormalizationSteps = []normalizationStep{
+	{name: "nfkd", transformer: norm.NFKD}, //unicode decomposition and replacing similar characters
+	{name: "map_runes", transformer: runes.Map(runeMapper)},
+	{name: "lowercase", transformer: runes.Map(unicode.ToLower)},
+	{name: "remove_zero_width", transformer: runes.Remove(runes.Predicate(isZeroWidthSpace))},
+	{name: "nfc", transformer: norm.NFC}, //return back for usual unicode form
+}
+
 func NormalizeString(s string) string {
-	normalizeTransform := transform.Chain(
-		norm.NFKD, //unicode decomposition and replacing similar characters
-		runes.Remove(runes.Predicate(unicode.IsMark)),
-		runes.Remove(runes.Predicate(unicode.IsSpace)),
-		runes.Remove(runes.Predicate(isZeroWidthSpace)),
-		runes.Map(runeMapper),
-		runes.Map(unicode.ToLower),
-		runes.Remove(runes.Predicate(unicode.IsPunct)),
-		norm.NFC, //return back for usual unicode form
-	)
-	s, _, _ = transform.String(normalizeTransform, s)
+	s, _, _ = transform.String(chainSteps(stringNormalizationSteps), s)
 	return unidecode.Unidecode(s)
 }
 
@@ -112,14 +123,16 @@ func runeMapper(r rune) rune {
 }
 
 func NormalizeComment(comment string) (string, error) {
-	normalizeTransform := transform.Chain(
-		norm.NFKD, //unicode decomposition and replacing similar characters
-		runes.Map(runeMapper),
-		runes.Map(unicode.ToLower),
-		runes.Remove(runes.Predicate(isZeroWidthSpace)),
-		norm.NFC, //return back for usual unicode form
-	)
-	comment, _, _ = transform.String(normalizeTransform, comment)
+	comment, _, _ = transform.String(chainSteps(commentNormalizationSteps), comment)
+	if _, ok := firstInvalidSymbol(comment); ok {
+		return "", fmt.Errorf("invalid charracter")
+	}
+	return comment, nil
+}
+
+// firstInvalidSymbol returns the first symbol of a normalized comment
+// which is neither an emoji nor one of WhiteSymbolsString.
+func firstInvalidSymbol(comment string) (rune, bool) {
 	for _, char := range comment {
 		if isSymbol := unicode.IsSymbol(char); !isSymbol {
 			continue
@@ -129,8 +142,8 @@ func NormalizeComment(comment string) (string, error) {
 			continue
 		}
 		if validSymbol := spamRegexp.whiteSymbolsRegexp.MatchString(humanChar); !validSymbol {
-			return "", fmt.Errorf("invalid charracter")
+			return char, true
 		}
 	}
-	return comment, nil
+	return 0, false
 }
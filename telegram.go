@@ -0,0 +1,212 @@
+package scam_backoffice_rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProtectedHandles are official telegram accounts used when no other list is configured.
+var DefaultProtectedHandles = []string{
+	"tonkeeper",
+	"tonkeeper_news",
+	"tonkeeper_ru",
+	"toncoin",
+	"tonblockchain",
+	"getgems",
+	"tonviewer",
+	"fragment",
+}
+
+// lookalikes of telegram domains like "t.mе" (cyrillic "е") are links to telegram too
+var (
+	telegramDomainSkeleton     = DomainSkeleton("t.me")
+	telegramLongDomainSkeleton = DomainSkeleton("telegram.me")
+)
+
+var telegramRegexp = struct {
+	mention *regexp.Regexp
+	link    *regexp.Regexp
+}{
+	// a mention must not be a part of an email or a link
+	mention: regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@./])@([\p{L}\p{N}_]{3,32})`),
+	link:    regexp.MustCompile(`^/(?:s/)?([\p{L}\p{N}_]{3,32})(?:[/?]|$)`),
+}
+
+type TypeOfHandleSource string
+
+const (
+	Mention      TypeOfHandleSource = "mention"
+	TelegramLink TypeOfHandleSource = "link"
+)
+
+// TelegramHandle is a telegram username mentioned in a text as "@handle" or "t.me/handle".
+type TelegramHandle struct {
+	// Raw is the handle as it is written in the text, without "@".
+	Raw string `json:"raw"`
+	// Normalized is Raw after NormalizeString.
+	Normalized string             `json:"normalized"`
+	Source     TypeOfHandleSource `json:"source"`
+}
+
+// ExtractTelegramHandles returns telegram usernames mentioned in a text.
+// Invite links like "t.me/+abc" and "t.me/joinchat/abc" are not usernames and are skipped.
+func ExtractTelegramHandles(text string) []TelegramHandle {
+	var handles []TelegramHandle
+	for _, match := range telegramRegexp.mention.FindAllStringSubmatch(text, -1) {
+		handles = append(handles, newTelegramHandle(match[1], Mention))
+	}
+	for _, url := range ExtractURLs(text) {
+		if domain := DomainSkeleton(url.Domain); domain != telegramDomainSkeleton && domain != telegramLongDomainSkeleton {
+			continue
+		}
+		match := telegramRegexp.link.FindStringSubmatch(url.Path)
+		if match == nil || strings.EqualFold(match[1], "joinchat") {
+			continue
+		}
+		handles = append(handles, newTelegramHandle(match[1], TelegramLink))
+	}
+	return handles
+}
+
+func newTelegramHandle(raw string, source TypeOfHandleSource) TelegramHandle {
+	return TelegramHandle{Raw: raw, Normalized: handleSkeleton(raw), Source: source}
+}
+
+func handleSkeleton(handle string) string {
+	return skeletonConfusables.Replace(NormalizeString(handle))
+}
+
+// HandleMatch describes a handle which imitates an official one.
+type HandleMatch struct {
+	Handle    TelegramHandle `json:"handle"`
+	Protected string         `json:"protected"`
+	// Score is a similarity between the handle and the official one, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+func (m HandleMatch) String() string {
+	return fmt.Sprintf("@%v impersonates @%v", m.Handle.Raw, m.Protected)
+}
+
+// HandleVerifier finds telegram handles imitating official ones,
+// like "@tonkeeper_supp0rt" or "t.me/tonkeeper_help_bot" imitating "@tonkeeper".
+type HandleVerifier struct {
+	// mu protects protected
+	mu        sync.RWMutex
+	protected []protectedHandle
+	// minScore is a similarity score starting from which a handle is reported.
+	minScore float64
+}
+
+type protectedHandle struct {
+	handle   string
+	skeleton string
+}
+
+type HandleOption func(v *HandleVerifier)
+
+// WithMinScore sets the similarity score starting from which a handle is reported, 0.8 by default.
+func WithMinScore(score float64) HandleOption {
+	return func(v *HandleVerifier) {
+		v.minScore = score
+	}
+}
+
+func NewHandleVerifier(handles []string, opts ...HandleOption) *HandleVerifier {
+	verifier := &HandleVerifier{minScore: 0.8}
+	for _, o := range opts {
+		o(verifier)
+	}
+	verifier.SetProtectedHandles(handles)
+	return verifier
+}
+
+// SetProtectedHandles replaces the list of official handles.
+func (v *HandleVerifier) SetProtectedHandles(handles []string) {
+	protected := make([]protectedHandle, 0, len(handles))
+	for _, handle := range handles {
+		handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+		protected = append(protected, protectedHandle{handle: handle, skeleton: handleSkeleton(handle)})
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.protected = protected
+}
+
+// LoadProtectedHandles replaces the list of official handles with the given yaml or json document:
+//
+//	protected_handles: ["tonkeeper", "getgems"]
+func (v *HandleVerifier) LoadProtectedHandles(bytesOfHandles []byte, yamlConverted bool) error {
+	var converted struct {
+		ProtectedHandles []string `yaml:"protected_handles" json:"protected_handles"`
+	}
+	var err error
+	if yamlConverted {
+		err = yaml.Unmarshal(bytesOfHandles, &converted)
+	} else {
+		err = json.Unmarshal(bytesOfHandles, &converted)
+	}
+	if err != nil {
+		return err
+	}
+	v.SetProtectedHandles(converted.ProtectedHandles)
+	return nil
+}
+
+// Check returns an official handle imitated by the given one.
+// Official handles themselves are never reported.
+func (v *HandleVerifier) Check(handle TelegramHandle) (HandleMatch, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, protected := range v.protected {
+		if strings.EqualFold(handle.Raw, protected.handle) {
+			return HandleMatch{}, false
+		}
+	}
+	best, found := HandleMatch{}, false
+	for _, protected := range v.protected {
+		score := handleSimilarity(handle.Normalized, protected.skeleton)
+		if score < v.minScore || (found && score <= best.Score) {
+			continue
+		}
+		best, found = HandleMatch{Handle: handle, Protected: protected.handle, Score: score}, true
+	}
+	return best, found
+}
+
+// CheckText returns the first handle mentioned in a text which imitates an official one.
+// The text must not be normalized, otherwise lookalike characters are already replaced.
+func (v *HandleVerifier) CheckText(text string) (HandleMatch, bool) {
+	for _, handle := range ExtractTelegramHandles(text) {
+		if match, ok := v.Check(handle); ok {
+			return match, true
+		}
+	}
+	return HandleMatch{}, false
+}
+
+// handleSimilarity scores how likely a user confuses the handle with the official one.
+// A handle containing the official one ("tonkeepersupport") is almost as bad as a lookalike.
+func handleSimilarity(handle, protected string) float64 {
+	if handle == protected {
+		return 1
+	}
+	length := utf8.RuneCountInString(protected)
+	if length == 0 {
+		return 0
+	}
+	if length >= 5 && strings.Contains(handle, protected) {
+		return 0.9
+	}
+	longest := length
+	if l := utf8.RuneCountInString(handle); l > longest {
+		longest = l
+	}
+	return 1 - float64(levenshtein(handle, protected))/float64(longest)
+}
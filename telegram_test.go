@@ -0,0 +1,50 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTelegramHandles(t *testing.T) {
+	handles := ExtractTelegramHandles("support: @Tonkeeper_Supp0rt, bot t[.]me/claim_ton_bot?start=1, chat t.me/+abcdef, mail me@gmail.com")
+	require.Equal(t, []TelegramHandle{
+		{Raw: "Tonkeeper_Supp0rt", Normalized: "tonkeepersupport", Source: Mention},
+		{Raw: "claim_ton_bot", Normalized: "clalmtonbot", Source: TelegramLink},
+	}, handles)
+}
+
+func TestHandleVerifier_CheckText(t *testing.T) {
+	tests := []struct {
+		text          string
+		wantProtected string
+		wantFound     bool
+	}{
+		{text: "news at @tonkeeper_news", wantFound: false},
+		{text: "news at t.me/tonkeeper", wantFound: false},
+		{text: "support: @tonkeeper_supp0rt", wantProtected: "tonkeeper", wantFound: true},
+		{text: "write to @tonkeepeer", wantProtected: "tonkeeper", wantFound: true},
+		{text: "write to @tоnkeeper", wantProtected: "tonkeeper", wantFound: true},
+		{text: "t.me/getgems_airdrop_bot", wantProtected: "getgems", wantFound: true},
+		{text: "t.mе/getgems_airdrop_bot", wantProtected: "getgems", wantFound: true},
+		{text: "follow @durov", wantFound: false},
+	}
+	verifier := NewHandleVerifier(DefaultProtectedHandles)
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			match, found := verifier.CheckText(tt.text)
+			require.Equal(t, tt.wantFound, found)
+			require.Equal(t, tt.wantProtected, match.Protected)
+		})
+	}
+}
+
+func TestImpersonatesHandleRule(t *testing.T) {
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: impersonates_handle
+    action: mark_scam
+`), true)
+	require.Equal(t, MarkScam, CheckAction(rules, "Your wallet is blocked, contact support: @tonkeeper_supp0rt"))
+	require.Equal(t, UnKnown, CheckAction(rules, "Subscribe to @tonkeeper_news"))
+}
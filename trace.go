@@ -0,0 +1,319 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/labstack/gommon/log"
+	"github.com/tonkeeper/tongo"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// Trace is a tree of transactions caused by one external message.
+// It mirrors txemulator.TxTree without importing the emulator, use TraceFrom to convert a TxTree.
+type Trace struct {
+	Transaction tlb.Transaction
+	Children    []*Trace
+}
+
+// TraceFrom converts a tree of another type to a Trace, for example an emulated txemulator.TxTree:
+//
+//	trace := TraceFrom(tree,
+//		func(t *txemulator.TxTree) tlb.Transaction { return t.TX },
+//		func(t *txemulator.TxTree) []*txemulator.TxTree { return t.Children })
+func TraceFrom[T any](root T, transaction func(T) tlb.Transaction, children func(T) []T) *Trace {
+	trace := &Trace{Transaction: transaction(root)}
+	for _, child := range children(root) {
+		trace.Children = append(trace.Children, TraceFrom(child, transaction, children))
+	}
+	return trace
+}
+
+// traceJSON is a serialized Trace with a transaction stored as a base64 BoC.
+type traceJSON struct {
+	Transaction string   `json:"transaction"`
+	Children    []*Trace `json:"children,omitempty"`
+}
+
+// transactionTLB mirrors tlb.Transaction without its cached hash, tlb.Marshal can't encode unexported fields.
+type transactionTLB struct {
+	Magic         tlb.Magic `tlb:"transaction$0111"`
+	AccountAddr   tlb.Bits256
+	Lt            uint64
+	PrevTransHash tlb.Bits256
+	PrevTransLt   uint64
+	Now           uint32
+	OutMsgCnt     tlb.Uint15
+	OrigStatus    tlb.AccountStatus
+	EndStatus     tlb.AccountStatus
+	Msgs          struct {
+		InMsg   tlb.Maybe[tlb.Ref[tlb.Message]]
+		OutMsgs tlb.HashmapE[tlb.Uint15, tlb.Ref[tlb.Message]]
+	} `tlb:"^"`
+	TotalFees   tlb.CurrencyCollection
+	StateUpdate tlb.HashUpdate       `tlb:"^"`
+	Description tlb.TransactionDescr `tlb:"^"`
+}
+
+func (t Trace) MarshalJSON() ([]byte, error) {
+	tx := t.Transaction
+	cell := boc.NewCell()
+	err := tlb.Marshal(cell, transactionTLB{
+		AccountAddr:   tx.AccountAddr,
+		Lt:            tx.Lt,
+		PrevTransHash: tx.PrevTransHash,
+		PrevTransLt:   tx.PrevTransLt,
+		Now:           tx.Now,
+		OutMsgCnt:     tx.OutMsgCnt,
+		OrigStatus:    tx.OrigStatus,
+		EndStatus:     tx.EndStatus,
+		Msgs:          tx.Msgs,
+		TotalFees:     tx.TotalFees,
+		StateUpdate:   tx.StateUpdate,
+		Description:   tx.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := cell.ToBocBase64()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(traceJSON{Transaction: transaction, Children: t.Children})
+}
+
+func (t *Trace) UnmarshalJSON(data []byte) error {
+	var converted traceJSON
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return err
+	}
+	cell, err := boc.DeserializeSinglRootBase64(converted.Transaction)
+	if err != nil {
+		return err
+	}
+	var transaction tlb.Transaction
+	if err := tlb.Unmarshal(cell, &transaction); err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	t.Transaction = transaction
+	t.Children = converted.Children
+	return nil
+}
+
+// JettonWalletResolver returns the jetton of a jetton wallet, so messages between jetton wallets
+// can be checked by JettonVerifier.
+type JettonWalletResolver interface {
+	JettonOfWallet(ctx context.Context, wallet tongo.AccountID) (Jetton, error)
+}
+
+// ErrUnknownJettonWallet is returned by a resolver which doesn't know a jetton wallet.
+var ErrUnknownJettonWallet = errors.New("unknown jetton wallet")
+
+// StaticJettonWallets maps jetton wallets to their jettons.
+type StaticJettonWallets map[tongo.AccountID]Jetton
+
+func (w StaticJettonWallets) JettonOfWallet(ctx context.Context, wallet tongo.AccountID) (Jetton, error) {
+	jetton, ok := w[wallet]
+	if !ok {
+		return Jetton{}, ErrUnknownJettonWallet
+	}
+	return jetton, nil
+}
+
+// MessageVerdict is the result of checks of one message of a trace.
+type MessageVerdict struct {
+	// Transaction is the hash of the transaction processing the message.
+	Transaction string           `json:"transaction"`
+	Lt          uint64           `json:"lt"`
+	Source      *tongo.AccountID `json:"source,omitempty"`
+	Destination *tongo.AccountID `json:"destination,omitempty"`
+	Comment     *MessageComment  `json:"comment,omitempty"`
+	// Action is the action of rules for the comment,
+	// MarkScam if the message transfers a jetton impersonating a well-known one.
+	Action TypeOfAction `json:"action"`
+	// Jetton is the verdict of JettonVerifier for a transferred jetton.
+	Jetton *JettonVerdict `json:"jetton,omitempty"`
+}
+
+// TraceVerdict aggregates verdicts of messages of a trace.
+type TraceVerdict struct {
+	// Action is the strongest action of all messages: MarkScam, Drop, Accept and UnKnown in this order.
+	Action TypeOfAction `json:"action"`
+	// Messages contains messages with comments or jettons in the order of the trace traversal.
+	Messages []MessageVerdict `json:"messages"`
+}
+
+var actionPriority = map[TypeOfAction]int{
+	UnKnown:  0,
+	Accept:   1,
+	Drop:     2,
+	MarkScam: 3,
+}
+
+func strongerAction(a, b TypeOfAction) TypeOfAction {
+	if actionPriority[b] > actionPriority[a] {
+		return b
+	}
+	return a
+}
+
+// TraceEvaluator applies comment rules and jetton checks to every message of a trace.
+type TraceEvaluator struct {
+	rules   Rules
+	jettons *JettonVerifier
+	wallets JettonWalletResolver
+}
+
+type TraceEvaluatorOption func(e *TraceEvaluator)
+
+// WithTraceJettonVerifier enables jetton checks of jetton transfers,
+// jettons are found by the wallets sending or receiving them.
+func WithTraceJettonVerifier(verifier *JettonVerifier, wallets JettonWalletResolver) TraceEvaluatorOption {
+	return func(e *TraceEvaluator) {
+		e.jettons = verifier
+		e.wallets = wallets
+	}
+}
+
+func NewTraceEvaluator(rules Rules, opts ...TraceEvaluatorOption) *TraceEvaluator {
+	e := &TraceEvaluator{rules: rules}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// Evaluate walks the trace depth-first and checks an incoming message of every transaction.
+func (e *TraceEvaluator) Evaluate(ctx context.Context, trace *Trace) TraceVerdict {
+	verdict := TraceVerdict{Action: UnKnown}
+	e.walk(ctx, trace, &verdict)
+	return verdict
+}
+
+func (e *TraceEvaluator) walk(ctx context.Context, trace *Trace, verdict *TraceVerdict) {
+	if trace == nil {
+		return
+	}
+	if message, ok := e.evaluateTransaction(ctx, &trace.Transaction); ok {
+		verdict.Messages = append(verdict.Messages, message)
+		verdict.Action = strongerAction(verdict.Action, message.Action)
+	}
+	for _, child := range trace.Children {
+		e.walk(ctx, child, verdict)
+	}
+}
+
+func (e *TraceEvaluator) evaluateTransaction(ctx context.Context, transaction *tlb.Transaction) (MessageVerdict, bool) {
+	if !transaction.Msgs.InMsg.Exists {
+		return MessageVerdict{}, false
+	}
+	msg := transaction.Msgs.InMsg.Value.Value
+	info := msg.Info.IntMsgInfo
+	if msg.Info.SumType != "IntMsgInfo" || info == nil {
+		// external messages are signed by wallet owners and carry no comments for them
+		return MessageVerdict{}, false
+	}
+	hash := transaction.Hash()
+	verdict := MessageVerdict{
+		Transaction: hash.Hex(),
+		Lt:          transaction.Lt,
+		Action:      UnKnown,
+	}
+	verdict.Source, _ = ton.AccountIDFromTlb(info.Src)
+	verdict.Destination, _ = ton.AccountIDFromTlb(info.Dest)
+
+	body := boc.Cell(msg.Body.Value)
+	found := false
+	if comment, err := ExtractComment(&body); err == nil {
+		found = true
+		verdict.Comment = &comment
+		sender := transferSender(body, verdict.Source)
+		switch {
+		case comment.Encrypted:
+		case sender != nil:
+			// rules like sender_reputation look at the sender
+			verdict.Action = CheckTransferContext(ctx, e.rules, Transfer{Sender: *sender, Comment: comment.Text})
+		default:
+			verdict.Action = CheckActionOfType(e.rules, comment.Text, Comment)
+		}
+	}
+	if jettonVerdict, ok := e.checkJetton(ctx, body, verdict.Source, verdict.Destination); ok {
+		found = true
+		verdict.Jetton = &jettonVerdict
+		if jettonVerdict.Severity == SeverityCritical {
+			verdict.Action = MarkScam
+		}
+	}
+	return verdict, found
+}
+
+// transferSender returns the wallet which initiated the transfer.
+// transfer_notification and ownership_assigned come from a jetton wallet or an NFT item,
+// the initiator is in their bodies. The source is returned for other messages.
+func transferSender(body boc.Cell, source *tongo.AccountID) *tongo.AccountID {
+	body.ResetCounters()
+	if body.BitsAvailableForRead() < 32 {
+		return source
+	}
+	op, err := body.ReadUint(32)
+	if err != nil {
+		return source
+	}
+	var sender tlb.MsgAddress
+	switch uint32(op) {
+	case jettonNotifyOpCode:
+		var msg jettonNotifyBody
+		if err := tlb.Unmarshal(&body, &msg); err != nil {
+			return source
+		}
+		sender = msg.Sender
+	case nftOwnershipAssignedOpCode:
+		var msg nftOwnershipAssignedBody
+		if err := tlb.Unmarshal(&body, &msg); err != nil {
+			return source
+		}
+		sender = msg.PrevOwner
+	default:
+		return source
+	}
+	account, err := ton.AccountIDFromTlb(sender)
+	if err != nil || account == nil {
+		return source
+	}
+	return account
+}
+
+// checkJetton checks a jetton of a jetton transfer, internal_transfer or transfer_notification.
+func (e *TraceEvaluator) checkJetton(ctx context.Context, body boc.Cell, source, destination *tongo.AccountID) (JettonVerdict, bool) {
+	if e.jettons == nil || e.wallets == nil {
+		return JettonVerdict{}, false
+	}
+	body.ResetCounters()
+	op, err := body.ReadUint(32)
+	if err != nil {
+		return JettonVerdict{}, false
+	}
+	wallet := destination
+	switch uint32(op) {
+	case jettonNotifyOpCode:
+		wallet = source
+	case jettonTransferOpCode, jettonInternalTransferOpCode:
+	default:
+		return JettonVerdict{}, false
+	}
+	if wallet == nil {
+		return JettonVerdict{}, false
+	}
+	jetton, err := e.wallets.JettonOfWallet(ctx, *wallet)
+	if err != nil {
+		if !errors.Is(err, ErrUnknownJettonWallet) {
+			log.Errorf("failed to resolve jetton wallet %v: %v", wallet.ToRaw(), err)
+		}
+		return JettonVerdict{}, false
+	}
+	return e.jettons.CheckJettonContext(ctx, jetton.Address, jetton.Name, jetton.Symbol), true
+}
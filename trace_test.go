@@ -0,0 +1,160 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tonkeeper/tongo"
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+func loadTrace(t *testing.T, path string) *Trace {
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	var trace Trace
+	require.Nil(t, json.Unmarshal(data, &trace))
+	return &trace
+}
+
+func testAddress(b byte) tongo.AccountID {
+	var address tongo.AccountID
+	address.Address[31] = b
+	return address
+}
+
+var testTraceRules = LoadRules([]byte(`
+rules:
+  - pattern: "^hello$"
+    action: accept
+    type: comment
+  - pattern: "voucher"
+    action: drop
+    type: comment
+`), true)
+
+func TestTraceEvaluator_Evaluate(t *testing.T) {
+	usdt := Jetton{Name: "Tether USD", Symbol: "USDT", Address: tongo.MustParseAccountID("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")}
+	fake := Jetton{Name: "Tether USD", Symbol: "USDT", Address: testAddress(0xf1)}
+	verifier := NewJettonVerifier(WithJettonSources(StaticJettonSource{usdt}))
+	defer verifier.Close()
+	require.Nil(t, verifier.Refresh(context.Background()))
+	wallets := StaticJettonWallets{testAddress(0xa2): fake, testAddress(0xb2): fake}
+	evaluator := NewTraceEvaluator(testTraceRules, WithTraceJettonVerifier(verifier, wallets))
+
+	verdict := evaluator.Evaluate(context.Background(), loadTrace(t, "testdata/trace_fake_jetton.json"))
+	require.Equal(t, MarkScam, verdict.Action)
+
+	type attribution struct {
+		lt          uint64
+		destination tongo.AccountID
+		source      TypeOfCommentSource
+		action      TypeOfAction
+		jetton      TypeOfJettonReason
+	}
+	var got []attribution
+	for _, message := range verdict.Messages {
+		require.NotNil(t, message.Comment)
+		require.NotEmpty(t, message.Transaction)
+		a := attribution{lt: message.Lt, destination: *message.Destination, source: message.Comment.Source, action: message.Action}
+		if message.Jetton != nil {
+			a.jetton = message.Jetton.Reason
+		}
+		got = append(got, a)
+	}
+	require.Equal(t, []attribution{
+		{lt: 102, destination: testAddress(0xa2), source: JettonTransferMessage, action: MarkScam, jetton: ImpersonatesJetton},
+		{lt: 104, destination: testAddress(0xb2), source: JettonTransferMessage, action: MarkScam, jetton: ImpersonatesJetton},
+		{lt: 106, destination: testAddress(0xb1), source: JettonTransferMessage, action: MarkScam, jetton: ImpersonatesJetton},
+		{lt: 102, destination: testAddress(0xc1), source: NftTransferMessage, action: Drop},
+		{lt: 104, destination: testAddress(0xb1), source: NftTransferMessage, action: Drop},
+	}, got)
+
+	withoutJettons := NewTraceEvaluator(testTraceRules).Evaluate(context.Background(), loadTrace(t, "testdata/trace_fake_jetton.json"))
+	require.Equal(t, Drop, withoutJettons.Action)
+	require.Len(t, withoutJettons.Messages, 5)
+	require.Nil(t, withoutJettons.Messages[0].Jetton)
+}
+
+func TestTraceEvaluator_GenuineJetton(t *testing.T) {
+	usdt := Jetton{Name: "Tether USD", Symbol: "USD₮", Address: tongo.MustParseAccountID("0:b113a994b5024a16719f69139328eb759596c38a25f59028b146fecdc3621dfe")}
+	verifier := NewJettonVerifier(WithJettonSources(StaticJettonSource{usdt}))
+	defer verifier.Close()
+	require.Nil(t, verifier.Refresh(context.Background()))
+	wallets := StaticJettonWallets{testAddress(0xa2): usdt, testAddress(0xb2): usdt}
+	evaluator := NewTraceEvaluator(testTraceRules, WithTraceJettonVerifier(verifier, wallets))
+
+	verdict := evaluator.Evaluate(context.Background(), loadTrace(t, "testdata/trace_jetton.json"))
+	require.Equal(t, Accept, verdict.Action)
+	require.Len(t, verdict.Messages, 3)
+	for _, message := range verdict.Messages {
+		require.Equal(t, Accept, message.Action)
+		require.NotNil(t, message.Jetton)
+		require.False(t, message.Jetton.Blacklisted())
+	}
+
+	silent := NewTraceEvaluator(LoadRules(nil, true), WithTraceJettonVerifier(verifier, wallets))
+	verdict = silent.Evaluate(context.Background(), loadTrace(t, "testdata/trace_jetton.json"))
+	require.Equal(t, UnKnown, verdict.Action)
+}
+
+func TestTraceEvaluator_TransferSender(t *testing.T) {
+	store := NewMemoryReputationStore(Reputation{Address: testAddress(0xa1), Label: ScamReputation})
+	rules := LoadRules([]byte(`
+rules:
+  - matcher: sender_reputation
+    labels: [scam]
+    action: mark_scam
+    type: comment
+`), true, WithReputationStore(store))
+
+	verdict := NewTraceEvaluator(rules).Evaluate(context.Background(), loadTrace(t, "testdata/trace_jetton.json"))
+	var got []TypeOfAction
+	for _, message := range verdict.Messages {
+		got = append(got, message.Action)
+	}
+	// the notification comes from the recipient's jetton wallet, the sender is in its body
+	require.Equal(t, []TypeOfAction{MarkScam, UnKnown, MarkScam}, got)
+}
+
+func TestTraceEvaluator_Comment(t *testing.T) {
+	verdict := NewTraceEvaluator(testTraceRules).Evaluate(context.Background(), loadTrace(t, "testdata/trace_comment.json"))
+	require.Equal(t, Accept, verdict.Action)
+	require.Len(t, verdict.Messages, 1)
+	require.Equal(t, "hello", verdict.Messages[0].Comment.Text)
+	require.Equal(t, testAddress(0xa1), *verdict.Messages[0].Source)
+}
+
+func TestTrace_JSON(t *testing.T) {
+	trace := loadTrace(t, "testdata/trace_fake_jetton.json")
+	data, err := json.Marshal(trace)
+	require.Nil(t, err)
+	var decoded Trace
+	require.Nil(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, trace.Transaction.Hash(), decoded.Transaction.Hash())
+	require.Len(t, decoded.Children, 2)
+	require.Equal(t, trace.Children[1].Children[0].Transaction.Hash(), decoded.Children[1].Children[0].Transaction.Hash())
+}
+
+func TestTraceFrom(t *testing.T) {
+	// txTree has the shape of txemulator.TxTree
+	type txTree struct {
+		TX       tlb.Transaction
+		Children []*txTree
+	}
+	var convert func(trace *Trace) *txTree
+	convert = func(trace *Trace) *txTree {
+		tree := &txTree{TX: trace.Transaction}
+		for _, child := range trace.Children {
+			tree.Children = append(tree.Children, convert(child))
+		}
+		return tree
+	}
+	original := loadTrace(t, "testdata/trace_fake_jetton.json")
+	trace := TraceFrom(convert(original),
+		func(t *txTree) tlb.Transaction { return t.TX },
+		func(t *txTree) []*txTree { return t.Children })
+	require.Equal(t, original, trace)
+}
@@ -0,0 +1,48 @@
+package scam_backoffice_rules
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/tonkeeper/tongo"
+)
+
+// Transfer is an incoming transfer checked by rules together with its comment.
+type Transfer struct {
+	Sender  tongo.AccountID
+	Comment string
+	// Amount is the transferred amount in the smallest units of the asset, like nanotons.
+	Amount *big.Int
+	// Jetton is the jetton master of the transferred asset, nil for TON.
+	Jetton *tongo.AccountID
+	// Decimals of the jetton, TON always has 9.
+	Decimals int
+	// Counterparties are addresses the recipient recently transacted with,
+	// they are used by the address_poisoning matcher.
+	Counterparties []tongo.AccountID
+}
+
+// CheckTransfer is CheckActionOfType for a comment of a transfer,
+// it also evaluates rules looking at the transfer itself, like address_poisoning.
+func CheckTransfer(rules Rules, transfer Transfer) TypeOfAction {
+	return CheckTransferContext(context.Background(), rules, transfer)
+}
+
+// CheckTransferContext is CheckTransfer with a context for lookups of rules like sender_reputation.
+func CheckTransferContext(ctx context.Context, rules Rules, transfer Transfer) TypeOfAction {
+	normalized, err := NormalizeComment(transfer.Comment)
+	if err != nil {
+		return Drop
+	}
+	input := ruleInput{raw: transfer.Comment, normalized: normalized, transfer: &transfer, ctx: ctx}
+	action := UnKnown
+	for _, rule := range rules {
+		if rule.Type == Comment || rule.Type == All {
+			action = rule.evaluate(input)
+			if action != UnKnown {
+				break
+			}
+		}
+	}
+	return action
+}
@@ -0,0 +1,127 @@
+package scam_backoffice_rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commonTLDs are used to glue "tonkeeper . com" back together.
+// We don't do it for any word after a dot because a usual sentence would turn into a domain.
+var commonTLDs = []string{
+	"com", "org", "net", "io", "me", "ton", "app", "xyz", "site", "online", "top", "ru", "cc", "co",
+	"info", "link", "live", "pro", "fun", "club", "shop", "store", "tech", "vip", "biz", "gift", "finance",
+}
+
+// bareHostTLDs are skeletons of TLDs of domains mentioned without a scheme, lookalikes like "t.mе" are domains too.
+// A host after a scheme can have any TLD, but "done.thanks" in a usual sentence is not a domain.
+var bareHostTLDs = func() map[string]struct{} {
+	tlds := map[string]struct{}{}
+	for _, tld := range commonTLDs {
+		tlds[labelSkeleton(tld)] = struct{}{}
+	}
+	for _, tld := range []string{
+		"dev", "ai", "gg", "icu", "cloud", "network", "world", "space", "website", "digital", "money",
+		"exchange", "cash", "click", "bot", "games", "social", "news", "eu", "de", "uk", "fr", "es", "nl",
+		"pl", "ua", "kz", "tr", "br", "jp", "cn", "kr", "vn", "sg", "ae", "ch", "рф",
+	} {
+		tlds[labelSkeleton(tld)] = struct{}{}
+	}
+	return tlds
+}()
+
+var urlRegexp = struct {
+	obfuscatedScheme *regexp.Regexp
+	bracketedDot     *regexp.Regexp
+	spacedDot        *regexp.Regexp
+	url              *regexp.Regexp
+}{
+	obfuscatedScheme: regexp.MustCompile(`(?i)\bh(?:xx|\*\*|tt)p(s?)\s*:\s*/\s*/`),
+	bracketedDot:     regexp.MustCompile(`(?i)\s*[\[({]\s*(?:\.|dot)\s*[\])}]\s*`),
+	spacedDot:        regexp.MustCompile(`(?i)([\p{L}\p{N}])(?:\s+\.\s*|\s*\.\s+|\s+dot\s+)(` + strings.Join(commonTLDs, "|") + `)\b`),
+	url: regexp.MustCompile(`(?i)(?:(https?|tg)://)?` +
+		`((?:[\p{L}\p{N}](?:[\p{L}\p{N}_-]*[\p{L}\p{N}])?\.)+(?:xn--[a-z0-9-]+|\p{L}{2,}))` +
+		`(?::\d{1,5})?(/[^\s<>"']*)?`),
+}
+
+// ExtractedURL is a link found in a text.
+type ExtractedURL struct {
+	// Raw is the link after deobfuscation.
+	Raw    string `json:"raw"`
+	Scheme string `json:"scheme,omitempty"`
+	// Host is the host as it is written in the text.
+	Host string `json:"host"`
+	// Domain is the canonical form of Host, see CanonicalDomain.
+	Domain string `json:"domain"`
+	TLD    string `json:"tld"`
+	Path   string `json:"path,omitempty"`
+}
+
+// deobfuscateURLs reverts common tricks used to hide links from filters:
+// "hxxp://", "tonkeeper[.]com", "tonkeeper(dot)com" and "tonkeeper . com".
+func deobfuscateURLs(text string) string {
+	text = urlRegexp.obfuscatedScheme.ReplaceAllString(text, "http$1://")
+	text = urlRegexp.bracketedDot.ReplaceAllString(text, ".")
+	return urlRegexp.spacedDot.ReplaceAllString(text, "$1.$2")
+}
+
+// ExtractURLs returns all links and bare domains mentioned in a text.
+// Bare domains must have a well-known TLD, links with a scheme can have any.
+// It works both with raw and normalized (see NormalizeComment) texts.
+func ExtractURLs(text string) []ExtractedURL {
+	text = deobfuscateURLs(text)
+	var urls []ExtractedURL
+	for _, match := range urlRegexp.url.FindAllStringSubmatch(text, -1) {
+		domain := CanonicalDomain(match[2])
+		url := ExtractedURL{
+			Raw:    match[0],
+			Scheme: strings.ToLower(match[1]),
+			Host:   strings.ToLower(match[2]),
+			Domain: domain,
+			TLD:    domain[strings.LastIndexByte(domain, '.')+1:],
+			Path:   match[3],
+		}
+		if _, ok := bareHostTLDs[labelSkeleton(url.TLD)]; !ok && url.Scheme == "" {
+			continue
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// ExtractDomains returns unique canonical domains mentioned in a text.
+func ExtractDomains(text string) []string {
+	var domains []string
+	seen := map[string]struct{}{}
+	for _, url := range ExtractURLs(text) {
+		if _, ok := seen[url.Domain]; ok {
+			continue
+		}
+		seen[url.Domain] = struct{}{}
+		domains = append(domains, url.Domain)
+	}
+	return domains
+}
+
+// CanonicalDomain converts a domain to the form used for comparison with domain lists:
+// it is lowercased, punycode labels are decoded and the trailing dot and "www." are removed.
+// Lookalike characters are kept, so "tonkeeрer.com" (cyrillic "р") is not "tonkeeper.com"
+// and can't pass an allowlist, see DomainSkeleton for comparing lookalikes.
+func CanonicalDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	domain = decodeDomain(domain)
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// parentDomains returns the domain itself and all its parents except the TLD:
+// "a.b.example.com" -> ["a.b.example.com", "b.example.com", "example.com"].
+func parentDomains(domain string) []string {
+	domains := []string{domain}
+	for {
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 || strings.IndexByte(domain[dot+1:], '.') < 0 {
+			return domains
+		}
+		domain = domain[dot+1:]
+		domains = append(domains, domain)
+	}
+}
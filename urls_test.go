@@ -0,0 +1,99 @@
+package scam_backoffice_rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractDomains(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Hello, how are you?", want: nil},
+		{text: "Claim at https://tonkeeper-gift.com/claim", want: []string{"tonkeeper-gift.com"}},
+		{text: "Claim at hxxps://tonkeeper-gift[.]com/claim", want: []string{"tonkeeper-gift.com"}},
+		{text: "visit tonkeeper-gift (dot) com now", want: []string{"tonkeeper-gift.com"}},
+		{text: "visit tonkeeper-gift . com now", want: []string{"tonkeeper-gift.com"}},
+		{text: "end of sentence. Next one", want: nil},
+		{text: "go to www.TONKEEPER.com", want: []string{"tonkeeper.com"}},
+		{text: "go to tonkeeрer.com", want: []string{"tonkeeрer.com"}},
+		{text: "go to xn--tonkeeer-bch.com", want: []string{"tonkeeрer.com"}},
+		{text: "go to ton100.org", want: []string{"ton100.org"}},
+		{text: "write to t.me/tonkeeper_support_bot", want: []string{"t.me"}},
+		{text: "a.com and b.org and a.com", want: []string{"a.com", "b.org"}},
+		{text: "I am done.thanks for the help. See you.bye", want: nil},
+		{text: "ok.Cool story,bro.Really", want: nil},
+		{text: "open https://claim.thanks/gift", want: []string{"claim.thanks"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			require.Equal(t, tt.want, ExtractDomains(tt.text))
+		})
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	urls := ExtractURLs("bot: t.me/scam_bot?start=1")
+	require.Len(t, urls, 1)
+	require.Equal(t, "t.me", urls[0].Host)
+	require.Equal(t, "me", urls[0].TLD)
+	require.Equal(t, "/scam_bot?start=1", urls[0].Path)
+}
+
+func TestCanonicalDomain(t *testing.T) {
+	require.Equal(t, "ton100.org", CanonicalDomain("TON100.org."))
+	require.Equal(t, "tonkeeper.com", CanonicalDomain("www.tonkeeper.com"))
+	require.Equal(t, "tonkeeрer.com", CanonicalDomain("xn--tonkeeer-bch.com"))
+}
+
+func TestDecodePunycode(t *testing.T) {
+	decoded, err := decodePunycode("e1afflejij")
+	require.Nil(t, err)
+	require.Equal(t, "тонкипер", decoded)
+	_, err = decodePunycode("!!")
+	require.NotNil(t, err)
+	require.Equal(t, "тонкипер.com", decodeDomain("xn--e1afflejij.com"))
+}
+
+func TestDomainRules(t *testing.T) {
+	lists := NewLists()
+	rules := LoadRules([]byte(`
+lists:
+  phishing: ["tonkeeper-gift.com"]
+  allowed: ["tonkeeper.com", "ton.org"]
+  bad_tlds: [".xyz"]
+rules:
+  - matcher: domain_in
+    list: phishing
+    action: mark_scam
+  - matcher: tld_in
+    list: bad_tlds
+    action: drop
+  - matcher: domain_not_in
+    list: allowed
+    action: drop
+`), true, WithLists(lists))
+	require.Len(t, rules, 3)
+
+	require.Equal(t, MarkScam, CheckAction(rules, "Claim at hxxps://app.tonkeeper-gift[.]com"))
+	require.Equal(t, Drop, CheckAction(rules, "airdrop.xyz"))
+	require.Equal(t, UnKnown, CheckAction(rules, "see docs at ton.org"))
+	require.Equal(t, Drop, CheckAction(rules, "see getgems.io"))
+
+	// lookalikes of allowed domains are not allowed
+	for _, text := range []string{"tonkeeрer.com", "t0nkeeper.com", "xn--tonkeeer-bch.com", "t0n.org"} {
+		require.Equal(t, Drop, CheckAction(rules, "see "+text), text)
+	}
+	// while lookalikes of blocked domains are still blocked
+	require.Equal(t, MarkScam, CheckAction(rules, "Claim at tonkeeреr-gift.com"))
+	require.Equal(t, MarkScam, CheckAction(rules, "Claim at t0nkeeper-gift.com"))
+	require.Equal(t, MarkScam, CheckAction(rules, "Claim at tonkeeper-glft.com"))
+	// usual sentences don't mention domains
+	require.Equal(t, UnKnown, CheckAction(rules, "I am done.thanks for the help. See you.bye"))
+
+	lists.Set("phishing", []string{"getgems-airdrop.io"})
+	require.Equal(t, MarkScam, CheckAction(rules, "getgems-airdrop.io"))
+	require.Equal(t, Drop, CheckAction(rules, "tonkeeper-gift.com"))
+}